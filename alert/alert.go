@@ -0,0 +1,114 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/config"
+	"github.com/nuklai/nuklaivm/consts"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Level is the severity of a balance alert
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "warning"
+	LevelCritical Level = "critical"
+)
+
+// Alert is sent as is to JSON webhooks
+type Alert struct {
+	Level     Level  `json:"level"`
+	Address   string `json:"address"`
+	Balance   uint64 `json:"balance"`
+	Threshold uint64 `json:"threshold"`
+	// RequestedRefill is the amount needed to get back above the warning
+	// threshold
+	RequestedRefill uint64 `json:"requestedRefill"`
+	// Depletion is the estimated number of seconds until the balance runs
+	// out at the recent payout rate, 0 if there were no recent payouts
+	Depletion int64  `json:"depletionSeconds"`
+	Paused    bool   `json:"paused"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// Text formats the alert for humans
+func (a *Alert) Text() string {
+	text := fmt.Sprintf("[%s] %s: balance of %s is %s %s",
+		a.Level, a.Message, a.Address, utils.FormatBalance(a.Balance, consts.Decimals), consts.Symbol)
+	if a.Level != LevelOK {
+		text += fmt.Sprintf(", please refill at least %s %s", utils.FormatBalance(a.RequestedRefill, consts.Decimals), consts.Symbol)
+	}
+	if a.Depletion > 0 {
+		text += fmt.Sprintf(", estimated depletion in %s", time.Duration(a.Depletion)*time.Second)
+	}
+	if a.Paused {
+		text += ", payouts are paused"
+	}
+	return text
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notifier delivers alerts to the configured webhooks
+type Notifier struct {
+	webhooks []config.Webhook
+	client   *http.Client
+}
+
+func NewNotifier(webhooks []config.Webhook) *Notifier {
+	return &Notifier{webhooks: webhooks, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Notify posts a to every webhook and returns the errors of the failed
+// deliveries
+func (n *Notifier) Notify(ctx context.Context, a *Alert) error {
+	var errs []error
+	for _, webhook := range n.webhooks {
+		if err := n.post(ctx, webhook, a); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) post(ctx context.Context, webhook config.Webhook, a *Alert) error {
+	var payload any = a
+	if webhook.Format == config.WebhookSlack {
+		payload = &slackMessage{Text: a.Text()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
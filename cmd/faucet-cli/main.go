@@ -0,0 +1,941 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// faucet-cli interacts with a Nuklai faucet over its JSON-RPC API
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/utils"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+
+	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+)
+
+type command struct {
+	usage string
+	admin bool
+	run   func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"address":      {usage: "address", run: runAddress},
+	"challenge":    {usage: "challenge", run: runChallenge},
+	"solve":        {usage: "solve [-workers n]", run: runSolve},
+	"claim":        {usage: "claim [-workers n] [-owner-key key] <address>", run: runClaim},
+	"update-rpc":   {usage: "update-rpc <url>", admin: true, run: runUpdateRPC},
+	"pause":        {usage: "pause [-message text] [-resume-at RFC3339]", admin: true, run: runPause},
+	"resume":       {usage: "resume", admin: true, run: runResume},
+	"stats":        {usage: "stats", admin: true, run: runStats},
+	"transactions": {usage: "transactions [-destination address] [-limit n]", admin: true, run: runTransactions},
+	"search":       {usage: "search [-ip ip] [-user-agent text] [-salt id] [-solution hash] [-since RFC3339] ... [-limit n]", admin: true, run: runSearch},
+	"deny":         {usage: "deny [-reason text] <address>", admin: true, run: runDeny},
+	"undeny":       {usage: "undeny <address>", admin: true, run: runUndeny},
+	"send-funds":   {usage: "send-funds -reason text [-amount n] <address>", admin: true, run: runSendFunds},
+	"deny-list":    {usage: "deny-list", admin: true, run: runDenyList},
+
+	"redeem":          {usage: "redeem <address> <code>", run: runRedeem},
+	"mint-vouchers":   {usage: "mint-vouchers [-count n] [-amount n] [-asset id] [-max-uses n] [-expires RFC3339]", admin: true, run: runMintVouchers},
+	"revoke-vouchers": {usage: "revoke-vouchers -batch id | -code code", admin: true, run: runRevokeVouchers},
+	"export-vouchers": {usage: "export-vouchers [-o file] <batch>", admin: true, run: runExportVouchers},
+
+	"claim-with-key": {usage: "claim-with-key [-api-key key] [-asset id] [-amount n] <address>", run: runClaimWithKey},
+	"create-api-key": {usage: "create-api-key [-daily-amount n] [-daily-requests n] [-assets ids] [-destinations addresses] <name>", admin: true, run: runCreateAPIKey},
+	"api-keys":       {usage: "api-keys", admin: true, run: runAPIKeys},
+	"revoke-api-key": {usage: "revoke-api-key <id>", admin: true, run: runRevokeAPIKey},
+	"api-key-usage":  {usage: "api-key-usage [-key id] [-days n]", admin: true, run: runAPIKeyUsage},
+
+	"airdrop":         {usage: "airdrop [-name text] [-asset id] [-batch n] [-retry-failed] [-o file] <file.csv|file.json> | -id id", admin: true, run: runAirdrop},
+	"airdrop-report":  {usage: "airdrop-report [-status status] [-o file] <id>", admin: true, run: runAirdropReport},
+	"airdrop-resolve": {usage: "airdrop-resolve -sent [-tx id] | -failed <id> <line>", admin: true, run: runAirdropResolve},
+}
+
+type cli struct {
+	client   *frpc.JSONRPCClient
+	adminKey ed25519.PrivateKey
+	json     bool
+}
+
+// output prints v as JSON, or calls human to print it for people
+func (c *cli) output(v any, human func(w *tabwriter.Writer)) error {
+	if c.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	human(w)
+	return w.Flush()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: faucet-cli [flags] <command> [args]\n\nFlags:\n")
+	flag.PrintDefaults()
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "\nCommands:\n")
+	for _, name := range names {
+		if !commands[name].admin {
+			fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\nAdmin commands (require -admin-key):\n")
+	for _, name := range names {
+		if commands[name].admin {
+			fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+		}
+	}
+}
+
+func main() {
+	endpoint := flag.String("endpoint", getEnv("FAUCET_ENDPOINT", "http://localhost:10591"), "faucet URL, ending with /<network> for a faucet serving several networks (env FAUCET_ENDPOINT)")
+	adminKey := flag.String("admin-key", os.Getenv("FAUCET_ADMIN_KEY"), "base64 ed25519 private key signing admin requests (env FAUCET_ADMIN_KEY)")
+	jsonOutput := flag.Bool("json", false, "print machine-readable JSON")
+	timeout := flag.Duration("timeout", 0, "abort after this duration (0 for no timeout)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	c := &cli{client: frpc.NewJSONRPCClient(*endpoint), json: *jsonOutput}
+	if cmd.admin {
+		key, err := parseAdminKey(*adminKey)
+		if err != nil {
+			utils.Outf("{{red}}invalid admin key{{/}}: %v\n", err)
+			os.Exit(1)
+		}
+		c.adminKey = key
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if err := cmd.run(ctx, c, flag.Args()[1:]); err != nil {
+		var ferr *frpc.Error
+		if c.json && errors.As(err, &ferr) {
+			_ = c.output(ferr, nil)
+		} else {
+			utils.Outf("{{red}}error{{/}}: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
+
+func parseAdminKey(value string) (ed25519.PrivateKey, error) {
+	if value == "" {
+		return ed25519.EmptyPrivateKey, errors.New("-admin-key or FAUCET_ADMIN_KEY must be set")
+	}
+	return parsePrivateKey(value)
+}
+
+func parsePrivateKey(value string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return ed25519.EmptyPrivateKey, err
+	}
+	if len(b) != ed25519.PrivateKeyLen {
+		return ed25519.EmptyPrivateKey, fmt.Errorf("private key must be %d bytes", ed25519.PrivateKeyLen)
+	}
+	return ed25519.PrivateKey(b), nil
+}
+
+// parseArgs parses the command flags and checks the number of positional
+// arguments
+func parseArgs(fs *flag.FlagSet, args []string, positional int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != positional {
+		return fmt.Errorf("expected %d argument(s), got %d", positional, fs.NArg())
+	}
+	return nil
+}
+
+func formatAmount(amount uint64) string {
+	return utils.FormatBalance(amount, nconsts.Decimals) + " " + nconsts.Symbol
+}
+
+// formatAssetAmount formats amount of the native asset, or of asset in base
+// units as its decimals are unknown
+func formatAssetAmount(amount uint64, asset string) string {
+	if asset == "" {
+		return formatAmount(amount)
+	}
+	return fmt.Sprintf("%d %s", amount, asset)
+}
+
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+func runAddress(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("address", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	addrs, err := c.client.FaucetAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("faucet returned no addresses")
+	}
+	return c.output(&frpc.FaucetAddressReply{Address: addrs[0], Addresses: addrs}, func(w *tabwriter.Writer) {
+		for _, addr := range addrs {
+			fmt.Fprintln(w, addr)
+		}
+	})
+}
+
+func runChallenge(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("challenge", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	reply, err := c.client.ChallengeInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return c.output(reply, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "salt:\t%s\n", base64.StdEncoding.EncodeToString(reply.Salt))
+		fmt.Fprintf(w, "difficulty:\t%d\n", reply.Difficulty)
+		fmt.Fprintf(w, "nonce:\t%s\n", base64.StdEncoding.EncodeToString(reply.Nonce))
+		fmt.Fprintf(w, "ownership proof:\t%t\n", reply.RequireOwnershipProof)
+		fmt.Fprintf(w, "paused:\t%t\n", reply.Paused)
+		if reply.Paused {
+			fmt.Fprintf(w, "message:\t%s\n", reply.Message)
+			fmt.Fprintf(w, "resume at:\t%s\n", formatTime(reply.ResumeAt))
+		}
+	})
+}
+
+// progress prints RequestFunds progress to stderr unless JSON output is
+// requested
+func (c *cli) progress() frpc.RequestOption {
+	return frpc.WithProgress(func(p frpc.Progress) {
+		if c.json {
+			return
+		}
+		switch p.Stage {
+		case frpc.StageSolving:
+			fmt.Fprintf(os.Stderr, "solving difficulty %d (%d attempts)\n", p.Difficulty, p.Attempts)
+		case frpc.StageWaiting, frpc.StageRetrying:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", p.Stage, p.Err)
+		case frpc.StageCompleted:
+		default:
+			fmt.Fprintf(os.Stderr, "%s\n", p.Stage)
+		}
+	})
+}
+
+type solveOutput struct {
+	Salt       []byte `json:"salt"`
+	Solution   []byte `json:"solution"`
+	Difficulty uint16 `json:"difficulty"`
+}
+
+func runSolve(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("solve", flag.ContinueOnError)
+	workers := fs.Int("workers", 0, "solver goroutines (default number of CPUs)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	opts := []frpc.RequestOption{c.progress()}
+	if *workers > 0 {
+		opts = append(opts, frpc.WithWorkers(*workers))
+	}
+	salt, solution, difficulty, err := c.client.Solve(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	out := &solveOutput{Salt: salt, Solution: solution, Difficulty: difficulty}
+	return c.output(out, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "salt:\t%s\n", base64.StdEncoding.EncodeToString(salt))
+		fmt.Fprintf(w, "solution:\t%s\n", base64.StdEncoding.EncodeToString(solution))
+		fmt.Fprintf(w, "difficulty:\t%d\n", difficulty)
+	})
+}
+
+func runClaim(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("claim", flag.ContinueOnError)
+	workers := fs.Int("workers", 0, "solver goroutines (default number of CPUs)")
+	ownerKey := fs.String("owner-key", os.Getenv("FAUCET_OWNER_KEY"), "base64 private key of the address, signing a proof of ownership (env FAUCET_OWNER_KEY)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	opts := []frpc.RequestOption{c.progress()}
+	if *workers > 0 {
+		opts = append(opts, frpc.WithWorkers(*workers))
+	}
+	if *ownerKey != "" {
+		key, err := parsePrivateKey(*ownerKey)
+		if err != nil {
+			return fmt.Errorf("invalid -owner-key: %w", err)
+		}
+		opts = append(opts, frpc.WithOwnershipKey(key))
+	}
+	txID, amount, err := c.client.RequestFunds(ctx, fs.Arg(0), opts...)
+	if err != nil {
+		return err
+	}
+	return c.output(&frpc.SolveChallengeReply{TxID: txID, Amount: amount}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "txID:\t%s\n", txID)
+		fmt.Fprintf(w, "amount:\t%s\n", formatAmount(amount))
+	})
+}
+
+type successOutput struct {
+	Success bool `json:"success"`
+}
+
+func (c *cli) success(ok bool, msg string) error {
+	return c.output(&successOutput{Success: ok}, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, msg)
+	})
+}
+
+func runUpdateRPC(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("update-rpc", flag.ContinueOnError)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	ok, err := c.client.UpdateNuklaiRPC(ctx, c.adminKey, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.success(ok, "Nuklai RPC updated")
+}
+
+func runPause(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("pause", flag.ContinueOnError)
+	message := fs.String("message", "", "maintenance message shown to users")
+	resumeAt := fs.String("resume-at", "", "expected resume time (RFC3339)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	var resumeAtUnix int64
+	if *resumeAt != "" {
+		t, err := time.Parse(time.RFC3339, *resumeAt)
+		if err != nil {
+			return fmt.Errorf("invalid -resume-at: %w", err)
+		}
+		resumeAtUnix = t.Unix()
+	}
+	ok, err := c.client.Pause(ctx, c.adminKey, *message, resumeAtUnix)
+	if err != nil {
+		return err
+	}
+	return c.success(ok, "Faucet paused")
+}
+
+func runResume(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("resume", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	ok, err := c.client.Resume(ctx, c.adminKey)
+	if err != nil {
+		return err
+	}
+	return c.success(ok, "Faucet resumed")
+}
+
+func runStats(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("stats", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	stats, err := c.client.Stats(ctx, c.adminKey)
+	if err != nil {
+		return err
+	}
+	return c.output(stats, func(w *tabwriter.Writer) {
+		if stats.Network != "" {
+			fmt.Fprintf(w, "network:\t%s\n", stats.Network)
+		}
+		fmt.Fprintf(w, "chain ID:\t%s\n", stats.ChainID)
+		fmt.Fprintf(w, "address:\t%s\n", stats.Address)
+		fmt.Fprintf(w, "balance:\t%s\n", formatAmount(stats.Balance))
+		fmt.Fprintf(w, "difficulty:\t%d\n", stats.Difficulty)
+		fmt.Fprintf(w, "paused:\t%t\n", stats.Paused)
+		fmt.Fprintf(w, "payouts:\t%d (%s)\n", stats.Transactions.TotalTransactions, formatAmount(stats.Transactions.TotalAmount))
+		fmt.Fprintf(w, "payouts (24h):\t%d (%s)\n", stats.Transactions.Last24hTransactions, formatAmount(stats.Transactions.Last24hAmount))
+		fmt.Fprintf(w, "unique destinations:\t%d\n", stats.Transactions.UniqueDestinations)
+		fmt.Fprintf(w, "last payout:\t%s\n", formatTime(stats.Transactions.LastTimestamp))
+		fmt.Fprintf(w, "refills:\t%d (%d failed, %d unconfirmed, %s)\n", stats.Refills.Attempts, stats.Refills.Failed, stats.Refills.Unconfirmed, formatAmount(stats.Refills.TotalAmount))
+		fmt.Fprintf(w, "refills (24h):\t%s\n", formatAmount(stats.Refills.Last24hAmount))
+		fmt.Fprintf(w, "last refill:\t%s\n", formatTime(stats.Refills.LastTimestamp))
+	})
+}
+
+func runTransactions(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("transactions", flag.ContinueOnError)
+	destination := fs.String("destination", "", "only list payouts to this address")
+	limit := fs.Int("limit", 20, "maximum number of transactions (0 for all)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	txs, err := c.client.Transactions(ctx, c.adminKey, *destination, *limit)
+	if err != nil {
+		return err
+	}
+	return c.output(txs, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TIME\tTXID\tDESTINATION\tAMOUNT")
+		for _, tx := range txs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatTime(tx.Timestamp), tx.TxID, tx.Destination, formatAmount(tx.Amount))
+		}
+	})
+}
+
+func runSearch(ctx context.Context, c *cli, args []string) error {
+	var (
+		filter database.TransactionFilter
+		since  string
+		until  string
+	)
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.StringVar(&filter.TxID, "txid", "", "only list the payout with this transaction ID")
+	fs.StringVar(&filter.Destination, "destination", "", "only list payouts to this address")
+	fs.StringVar(&filter.Admin, "admin", "", "only list payouts sent by this admin key name")
+	difficulty := fs.Uint("difficulty", 0, "only list payouts of solutions of this difficulty")
+	fs.StringVar(&filter.SaltID, "salt", "", "only list payouts of solutions to this salt ID")
+	fs.StringVar(&filter.SolutionHash, "solution", "", "only list the payout of this solution hash")
+	fs.StringVar(&filter.ClientIP, "ip", "", "only list payouts requested from this IP")
+	fs.StringVar(&filter.UserAgent, "user-agent", "", "only list payouts whose user agent contains this text")
+	fs.StringVar(&filter.RPCEndpoint, "rpc", "", "only list payouts sent through this RPC endpoint")
+	fs.Uint64Var(&filter.MinFee, "min-fee", 0, "only list payouts with at least this max fee")
+	fs.Uint64Var(&filter.MaxFee, "max-fee", 0, "only list payouts with at most this max fee")
+	fs.Int64Var(&filter.MinProcessingTime, "min-processing-ms", 0, "only list payouts processed in at least this many milliseconds")
+	fs.Int64Var(&filter.MaxProcessingTime, "max-processing-ms", 0, "only list payouts processed in at most this many milliseconds")
+	fs.StringVar(&since, "since", "", "only list payouts at or after this time (RFC3339)")
+	fs.StringVar(&until, "until", "", "only list payouts at or before this time (RFC3339)")
+	fs.IntVar(&filter.Limit, "limit", 20, "maximum number of transactions (0 for all)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *difficulty > math.MaxUint16 {
+		return fmt.Errorf("invalid -difficulty: %d", *difficulty)
+	}
+	filter.Difficulty = uint16(*difficulty)
+	for _, bound := range []struct {
+		name  string
+		value string
+		unix  *int64
+	}{{"since", since, &filter.Since}, {"until", until, &filter.Until}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return fmt.Errorf("invalid -%s: %w", bound.name, err)
+		}
+		*bound.unix = t.Unix()
+	}
+	txs, err := c.client.SearchTransactions(ctx, c.adminKey, filter)
+	if err != nil {
+		return err
+	}
+	return c.output(txs, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TIME\tTXID\tDESTINATION\tAMOUNT\tFEE\tDIFFICULTY\tCLIENT IP\tPROCESSING\tUSER AGENT")
+		for _, tx := range txs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				formatTime(tx.Timestamp), tx.TxID, tx.Destination, formatAmount(tx.Amount), formatAmount(tx.Fee),
+				tx.Difficulty, tx.ClientIP, time.Duration(tx.ProcessingTime)*time.Millisecond, tx.UserAgent)
+		}
+	})
+}
+
+func runDeny(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("deny", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the address is denied")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	ok, err := c.client.DenyAddress(ctx, c.adminKey, fs.Arg(0), *reason)
+	if err != nil {
+		return err
+	}
+	return c.success(ok, "Address denied")
+}
+
+func runUndeny(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("undeny", flag.ContinueOnError)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	ok, err := c.client.RemoveDeniedAddress(ctx, c.adminKey, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.success(ok, "Address removed from deny list")
+}
+
+func runDenyList(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("deny-list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	denied, err := c.client.DeniedAddresses(ctx, c.adminKey)
+	if err != nil {
+		return err
+	}
+	return c.output(denied, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TIME\tADDRESS\tREASON")
+		for _, d := range denied {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", formatTime(d.Timestamp), d.Address, strings.ReplaceAll(d.Reason, "\n", " "))
+		}
+	})
+}
+
+func runRedeem(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("redeem", flag.ContinueOnError)
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+	txID, amount, asset, err := c.client.RedeemVoucher(ctx, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return c.output(&frpc.RedeemVoucherReply{TxID: txID, Amount: amount, Asset: asset}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "txID:\t%s\n", txID)
+		fmt.Fprintf(w, "amount:\t%s\n", formatAssetAmount(amount, asset))
+	})
+}
+
+func runMintVouchers(ctx context.Context, c *cli, args []string) error {
+	var mint frpc.MintVouchersArgs
+	fs := flag.NewFlagSet("mint-vouchers", flag.ContinueOnError)
+	fs.IntVar(&mint.Count, "count", 1, "number of vouchers")
+	fs.Uint64Var(&mint.Amount, "amount", 0, "amount paid by each voucher in base units (default the faucet amount)")
+	fs.StringVar(&mint.Asset, "asset", "", "asset ID paid out (default the native asset)")
+	maxUses := fs.Uint("max-uses", 1, "number of addresses that can redeem each voucher")
+	expires := fs.String("expires", "", "expiry time (RFC3339, default never)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *maxUses > math.MaxUint32 {
+		return fmt.Errorf("invalid -max-uses: %d", *maxUses)
+	}
+	mint.MaxUses = uint32(*maxUses)
+	if *expires != "" {
+		t, err := time.Parse(time.RFC3339, *expires)
+		if err != nil {
+			return fmt.Errorf("invalid -expires: %w", err)
+		}
+		mint.ExpiresAt = t.Unix()
+	}
+	reply, err := c.client.MintVouchers(ctx, c.adminKey, mint)
+	if err != nil {
+		return err
+	}
+	return c.output(reply, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "batch:\t%s\n", reply.BatchID)
+		for _, v := range reply.Vouchers {
+			fmt.Fprintf(w, "%s\t%s\n", v.Code, formatAssetAmount(v.Amount, v.Asset))
+		}
+	})
+}
+
+type revokeOutput struct {
+	Revoked int64 `json:"revoked"`
+}
+
+func runRevokeVouchers(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("revoke-vouchers", flag.ContinueOnError)
+	batchID := fs.String("batch", "", "revoke every voucher of this batch")
+	code := fs.String("code", "", "revoke this voucher")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if (*batchID == "") == (*code == "") {
+		return errors.New("exactly one of -batch and -code must be set")
+	}
+	revoked, err := c.client.RevokeVouchers(ctx, c.adminKey, *batchID, *code)
+	if err != nil {
+		return err
+	}
+	return c.output(&revokeOutput{Revoked: revoked}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "%d voucher(s) revoked\n", revoked)
+	})
+}
+
+func runExportVouchers(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("export-vouchers", flag.ContinueOnError)
+	out := fs.String("o", "", "write the CSV to this file instead of stdout")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	data, err := c.client.ExportVouchers(ctx, c.adminKey, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, []byte(data), 0o600)
+	}
+	_, err = fmt.Print(data)
+	return err
+}
+
+func runClaimWithKey(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("claim-with-key", flag.ContinueOnError)
+	apiKey := fs.String("api-key", os.Getenv("FAUCET_API_KEY"), "API key (env FAUCET_API_KEY)")
+	asset := fs.String("asset", "", "asset ID to claim (default the native asset)")
+	amount := fs.Uint64("amount", 0, "amount to claim in base units (default the faucet amount, at most that for the native asset)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if *apiKey == "" {
+		return errors.New("-api-key or FAUCET_API_KEY must be set")
+	}
+	txID, paid, err := c.client.ClaimWithAPIKey(ctx, *apiKey, fs.Arg(0), *asset, *amount)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(*asset, nconsts.Symbol) {
+		*asset = ""
+	}
+	return c.output(&frpc.ClaimWithAPIKeyReply{TxID: txID, Amount: paid}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "txID:\t%s\n", txID)
+		fmt.Fprintf(w, "amount:\t%s\n", formatAssetAmount(paid, *asset))
+	})
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func formatQuota(quota uint64, format func(uint64) string) string {
+	if quota == 0 {
+		return "unlimited"
+	}
+	return format(quota)
+}
+
+func formatCount(n uint64) string {
+	return fmt.Sprint(n)
+}
+
+func runCreateAPIKey(ctx context.Context, c *cli, args []string) error {
+	var create frpc.CreateAPIKeyArgs
+	fs := flag.NewFlagSet("create-api-key", flag.ContinueOnError)
+	fs.Uint64Var(&create.DailyAmount, "daily-amount", 0, "amount the key can claim per UTC day in base units (0 for no limit)")
+	dailyRequests := fs.Uint("daily-requests", 0, "claims the key can make per UTC day (0 for no limit)")
+	assets := fs.String("assets", "", "comma separated asset IDs, or NAI, the key can claim (default only the native asset)")
+	destinations := fs.String("destinations", "", "comma separated addresses the key can pay (default any)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if *dailyRequests > math.MaxUint32 {
+		return fmt.Errorf("invalid -daily-requests: %d", *dailyRequests)
+	}
+	create.Name = fs.Arg(0)
+	create.DailyRequests = uint32(*dailyRequests)
+	create.AllowedAssets = splitList(*assets)
+	create.AllowedDestinations = splitList(*destinations)
+	reply, err := c.client.CreateAPIKey(ctx, c.adminKey, create)
+	if err != nil {
+		return err
+	}
+	return c.output(reply, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "id:\t%s\n", reply.APIKey.ID)
+		fmt.Fprintf(w, "key:\t%s\n", reply.Key)
+		fmt.Fprintln(w, "\nThe key is not shown again, store it now.")
+	})
+}
+
+func runAPIKeys(ctx context.Context, c *cli, args []string) error {
+	if err := parseArgs(flag.NewFlagSet("api-keys", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	keys, err := c.client.APIKeys(ctx, c.adminKey)
+	if err != nil {
+		return err
+	}
+	return c.output(keys, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tDAILY AMOUNT\tDAILY REQUESTS\tASSETS\tDESTINATIONS\tCREATED\tREVOKED")
+		for _, k := range keys {
+			assets, destinations := strings.Join(k.AllowedAssets, ","), strings.Join(k.AllowedDestinations, ",")
+			if assets == "" {
+				assets = nconsts.Symbol
+			}
+			if destinations == "" {
+				destinations = "any"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name,
+				formatQuota(k.DailyAmount, formatAmount), formatQuota(uint64(k.DailyRequests), formatCount),
+				assets, destinations, formatTime(k.CreatedAt), formatTime(k.RevokedAt))
+		}
+	})
+}
+
+func runRevokeAPIKey(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("revoke-api-key", flag.ContinueOnError)
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	ok, err := c.client.RevokeAPIKey(ctx, c.adminKey, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return c.success(ok, "API key unknown or already revoked")
+	}
+	return c.success(ok, "API key revoked")
+}
+
+func runAPIKeyUsage(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("api-key-usage", flag.ContinueOnError)
+	id := fs.String("key", "", "only report the usage of this key ID")
+	days := fs.Int("days", 7, "number of days to report, including today")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+	usage, err := c.client.APIKeyUsage(ctx, c.adminKey, *id, *days)
+	if err != nil {
+		return err
+	}
+	return c.output(usage, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "DAY\tKEY\tREQUESTS\tAMOUNT")
+		for _, u := range usage {
+			day := time.Unix(u.Day*int64(24*time.Hour/time.Second), 0).UTC().Format(time.DateOnly)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", day, u.KeyID, u.Requests, formatAmount(u.Amount))
+		}
+	})
+}
+
+func runSendFunds(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("send-funds", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the funds are sent, recorded with the payout")
+	amount := fs.Uint64("amount", 0, "amount to send in base units (default the faucet amount)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if *reason == "" {
+		return errors.New("-reason must be set")
+	}
+	txID, sent, err := c.client.SendFunds(ctx, c.adminKey, fs.Arg(0), *amount, *reason)
+	if err != nil {
+		return err
+	}
+	return c.output(&frpc.SendFundsReply{TxID: txID, Amount: sent}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "txID:\t%s\n", txID)
+		fmt.Fprintf(w, "amount:\t%s\n", formatAmount(sent))
+	})
+}
+
+func runAirdrop(ctx context.Context, c *cli, args []string) error {
+	var create frpc.CreateAirdropArgs
+	fs := flag.NewFlagSet("airdrop", flag.ContinueOnError)
+	fs.StringVar(&create.Name, "name", "", "name of the airdrop (default the file name)")
+	fs.StringVar(&create.Asset, "asset", "", "asset ID paid out (default the native asset)")
+	id := fs.String("id", "", "resume this airdrop instead of creating one")
+	batch := fs.Int("batch", 0, "rows sent concurrently (default 20)")
+	retryFailed := fs.Bool("retry-failed", false, "send the failed rows again")
+	out := fs.String("o", "", "also write the report as CSV to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *id == "" && fs.NArg() != 1:
+		return errors.New("expected a CSV or JSON file, or -id to resume an airdrop")
+	case *id != "" && fs.NArg() != 0:
+		return errors.New("-id resumes an airdrop and takes no file")
+	}
+
+	if *id == "" {
+		if err := readAirdropFile(fs.Arg(0), &create); err != nil {
+			return err
+		}
+		if create.Name == "" {
+			create.Name = filepath.Base(fs.Arg(0))
+		}
+		reply, err := c.client.CreateAirdrop(ctx, c.adminKey, create)
+		if err != nil {
+			return err
+		}
+		*id = reply.Airdrop.ID
+		if !c.json {
+			fmt.Fprintf(os.Stderr, "created airdrop %s with %d row(s), resume with -id %s if interrupted\n", *id, reply.Summary.Rows, *id)
+		}
+	}
+
+	retry := *retryFailed
+	for {
+		reply, err := c.client.SendAirdrop(ctx, c.adminKey, *id, *batch, retry)
+		if err != nil {
+			return fmt.Errorf("airdrop %s stopped, resume with -id %s: %w", *id, *id, err)
+		}
+		retry = false
+		if len(reply.Rows) == 0 {
+			break
+		}
+		if !c.json {
+			sum := reply.Summary
+			fmt.Fprintf(os.Stderr, "sent %d/%d row(s), %d failed, %d unconfirmed\n", sum.Sent, sum.Rows, sum.Failed, sum.Unconfirmed)
+		}
+	}
+
+	report, err := c.client.AirdropReport(ctx, c.adminKey, *id, "")
+	if err != nil {
+		return err
+	}
+	return c.airdropReport(report, *out)
+}
+
+// readAirdropFile sets the recipients of args from a JSON list of
+// {"address", "amount"} objects, or from CSV records of an address and an
+// optional amount
+func readAirdropFile(path string, args *frpc.CreateAirdropArgs) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &args.Recipients); err != nil {
+			return fmt.Errorf("invalid airdrop JSON: %w", err)
+		}
+		return nil
+	}
+	args.CSV = string(data)
+	return nil
+}
+
+func runAirdropReport(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("airdrop-report", flag.ContinueOnError)
+	status := fs.String("status", "", "only list the rows with this status (pending, sending, sent, failed or unconfirmed)")
+	out := fs.String("o", "", "also write the report as CSV to this file")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	report, err := c.client.AirdropReport(ctx, c.adminKey, fs.Arg(0), *status)
+	if err != nil {
+		return err
+	}
+	return c.airdropReport(report, *out)
+}
+
+func runAirdropResolve(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("airdrop-resolve", flag.ContinueOnError)
+	sent := fs.Bool("sent", false, "the transfer of the row was accepted")
+	failed := fs.Bool("failed", false, "the transfer of the row was not accepted, retry-failed sends it again")
+	txID := fs.String("tx", "", "transaction ID of a sent row, if not recorded")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+	if *sent == *failed {
+		return errors.New("expected one of -sent or -failed")
+	}
+	line, err := strconv.Atoi(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("invalid line %q", fs.Arg(1))
+	}
+	row, err := c.client.ResolveAirdropRow(ctx, c.adminKey, fs.Arg(0), line, *sent, *txID)
+	if err != nil {
+		return err
+	}
+	return c.output(row, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "line:\t%d\n", row.Line)
+		fmt.Fprintf(w, "address:\t%s\n", row.Address)
+		fmt.Fprintf(w, "status:\t%s\n", row.Status)
+		if row.TxID != "" {
+			fmt.Fprintf(w, "txID:\t%s\n", row.TxID)
+		}
+	})
+}
+
+// airdropReportCSVHeader names the columns of airdrop reports
+var airdropReportCSVHeader = []string{"line", "address", "amount", "status", "txid", "error"}
+
+// airdropReport prints report, and writes its rows as CSV to out if set
+func (c *cli) airdropReport(report *frpc.AirdropReportReply, out string) error {
+	if out != "" {
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		if err := w.Write(airdropReportCSVHeader); err != nil {
+			return err
+		}
+		for _, row := range report.Rows {
+			record := []string{strconv.Itoa(row.Line), row.Address, strconv.FormatUint(row.Amount, 10), row.Status, row.TxID, row.Error}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, []byte(b.String()), 0o600); err != nil {
+			return err
+		}
+	}
+	a, sum := report.Airdrop, report.Summary
+	return c.output(report, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "airdrop:\t%s (%s)\n", a.ID, a.Name)
+		fmt.Fprintf(w, "created:\t%s by %s\n", formatTime(a.CreatedAt), a.CreatedBy)
+		fmt.Fprintf(w, "rows:\t%d sent, %d failed, %d unconfirmed, %d pending, %d sending of %d\n", sum.Sent, sum.Failed, sum.Unconfirmed, sum.Pending, sum.Sending, sum.Rows)
+		fmt.Fprintf(w, "sent:\t%s\n", formatAssetAmount(sum.SentAmount, a.Asset))
+		if len(report.Rows) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "\nLINE\tADDRESS\tAMOUNT\tSTATUS\tTX ID / ERROR")
+		for _, row := range report.Rows {
+			result := row.TxID
+			switch row.Status {
+			case database.AirdropFailed:
+				result = row.Error
+			case database.AirdropUnconfirmed:
+				if result == "" {
+					result = row.Error
+				}
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", row.Line, row.Address, formatAssetAmount(row.Amount, a.Asset), row.Status, result)
+		}
+	})
+}
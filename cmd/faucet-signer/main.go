@@ -0,0 +1,170 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// faucet-signer is a reference remote signer for the Nuklai faucet. It holds
+// the faucet keys, read from encrypted keystores, and signs the transfers
+// the faucet sends over a Unix socket as long as they comply with its policy.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklaivm/auth"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+
+	"github.com/nuklai/nuklai-faucet/keystore"
+	"github.com/nuklai/nuklai-faucet/signer"
+)
+
+func main() {
+	socket := flag.String("socket", "faucet-signer.sock", "Unix socket to listen on, set as SIGNER_SOCKET of the faucet")
+	keystores := flag.String("keystore", "", "comma separated keystore files of the faucet keys, the primary wallet first")
+	passphraseFile := flag.String("passphrase-file", "", "file holding the passphrase of the keystores, KEYSTORE_PASSPHRASE or a prompt otherwise")
+	maxAmount := flag.Uint64("max-amount", 0, "largest amount a transaction may transfer, 0 for no limit")
+	allowedAssets := flag.String("allowed-assets", "", "comma separated asset IDs that may be transferred, only the native asset if empty")
+	flag.Parse()
+
+	if err := run(*socket, *keystores, *passphraseFile, *maxAmount, *allowedAssets); err != nil {
+		utils.Outf("{{red}}error{{/}}: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(socket, keystores, passphraseFile string, maxAmount uint64, allowedAssets string) error {
+	if keystores == "" {
+		flag.Usage()
+		return errors.New("-keystore is required")
+	}
+	policy := signer.Policy{MaxAmount: maxAmount}
+	for _, entry := range strings.Split(allowedAssets, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		asset, err := ids.FromString(entry)
+		if err != nil {
+			return fmt.Errorf("invalid asset %q: %w", entry, err)
+		}
+		policy.AllowedAssets = append(policy.AllowedAssets, asset)
+	}
+
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+	var keys []ed25519.PrivateKey
+	for _, path := range strings.Split(keystores, ",") {
+		key, err := keystore.ReadFile(strings.TrimSpace(path), passphrase)
+		if err != nil {
+			return fmt.Errorf("cannot read keystore %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	logFactory := logging.NewFactory(logging.Config{
+		DisplayLevel: logging.Info,
+	})
+	log, err := logFactory.Make("signer")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		log.Info("Key loaded", zap.String("address", keystore.Address(key)))
+	}
+	log.Info("Policy loaded", zap.Uint64("maxAmount", policy.MaxAmount), zap.Stringers("allowedAssets", policy.AllowedAssets))
+
+	handler, err := signer.NewHandler(&auditSigner{Signer: signer.NewLocal(keys, policy), log: log})
+	if err != nil {
+		return err
+	}
+	// A socket left behind by a previous run would make Listen fail
+	if info, err := os.Lstat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socket); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	// Only the user running the signer, and so the faucet, may connect
+	if err := os.Chmod(socket, 0o600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.Info("Triggering signer shutdown", zap.Any("signal", sig))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	log.Info("Signer listening", zap.String("socket", socket))
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Info("Signer exited")
+	return nil
+}
+
+// auditSigner logs every signature request and its outcome
+type auditSigner struct {
+	signer.Signer
+	log logging.Logger
+}
+
+func (a *auditSigner) Sign(ctx context.Context, pk ed25519.PublicKey, digest []byte) (ed25519.Signature, error) {
+	address := codec.MustAddressBech32(nconsts.HRP, auth.NewED25519Address(pk))
+	sig, err := a.Signer.Sign(ctx, pk, digest)
+	if err != nil {
+		a.log.Warn("Refused to sign", zap.String("address", address), zap.Error(err))
+		return sig, err
+	}
+	a.log.Info("Signed transaction", zap.String("address", address), zap.Stringer("digest", utils.ToID(digest)))
+	return sig, nil
+}
+
+// readPassphrase reads the passphrase of the keystores from path, the
+// environment or a prompt
+func readPassphrase(path string) ([]byte, error) {
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(b, "\r\n"), nil
+	}
+	if passphrase, ok := os.LookupEnv("KEYSTORE_PASSPHRASE"); ok {
+		return []byte(passphrase), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no passphrase, set -passphrase-file or KEYSTORE_PASSPHRASE")
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return passphrase, err
+}
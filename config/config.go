@@ -4,29 +4,139 @@
 package config
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
-	"strconv"
+	"slices"
+	"strings"
 
+	"github.com/ava-labs/avalanchego/trace"
 	"github.com/ava-labs/hypersdk/codec"
 	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/nuklai/nuklaivm/auth"
 	"github.com/nuklai/nuklaivm/consts"
 )
 
+// Admin roles, from least to most privileged. A key may call any admin
+// method that requires its role or a less privileged one.
+const (
+	RoleViewer     = "viewer"
+	RoleOperator   = "operator"
+	RoleSuperAdmin = "superadmin"
+)
+
+var adminRoles = map[string]int{
+	RoleViewer:     1,
+	RoleOperator:   2,
+	RoleSuperAdmin: 3,
+}
+
+// AdminKey is an ed25519 public key allowed to sign admin requests
+type AdminKey struct {
+	Name      string
+	Role      string
+	PublicKey ed25519.PublicKey
+}
+
+// HasRole reports whether the key is allowed to act as role
+func (k *AdminKey) HasRole(role string) bool {
+	return adminRoles[k.Role] >= adminRoles[role]
+}
+
+// Webhook formats
+const (
+	WebhookJSON  = "json"
+	WebhookSlack = "slack"
+)
+
+// Webhook is an endpoint notified of balance alerts
+type Webhook struct {
+	Format string
+	URL    string
+}
+
+// Database backends
+const (
+	DatabasePostgres = "postgres"
+	DatabaseSQLite   = "sqlite"
+	DatabaseMemory   = "memory"
+)
+
+// defaultPrivateKey is the well-known key published in .env.example, only
+// allowed in dev mode
+const defaultPrivateKey = "Mjsdj07tXw2p2pMHGwNPLc6dLSJpLBcvPLJSpk3fr9AbBX3jICl8Ka0MH1ieohaGnPGTjYjJ+9cNZ0gyPb8vpw=="
+
 type Config struct {
 	HTTPHost string
 	HTTPPort int
 
+	// Network is the name of the network the config is for, empty unless
+	// NETWORKS is set
+	Network string
+	// Networks are the configs of the networks served when NETWORKS is set,
+	// each one made of the shared settings and its overrides
+	Networks []*Config
+
 	PrivateKeyBytes []byte
+	// KeystoreFile is the encrypted keystore PrivateKeyBytes was read from,
+	// empty if the key is set directly
+	KeystoreFile string
+	// DevMode allows the well-known default private key
+	DevMode bool
+	// SignerSocket is the Unix socket of a remote signer holding the faucet
+	// keys, in which case PrivateKeyBytes is empty
+	SignerSocket string
+	// AdditionalPrivateKeys join PrivateKeyBytes in the pool of faucet
+	// wallets that payouts are spread across
+	AdditionalPrivateKeys [][]byte
 
 	NuklaiRPC             string
 	Amount                uint64
 	StartDifficulty       uint16
 	SolutionsPerSalt      int
 	TargetDurationPerSalt int64 // seconds
+	// SolutionTTL is how long used solutions are remembered to reject
+	// replays
+	SolutionTTL int64 // seconds
+	// RequireOwnershipProof only pays solutions signed by the key of the
+	// destination address
+	RequireOwnershipProof bool
+
+	// MinBalance is the balance below which the faucet reports not ready
+	MinBalance uint64
 
-	AdminToken string
+	// Balance monitoring
+	BalanceCheckInterval     int64 // seconds
+	BalanceWarningThreshold  uint64
+	BalanceCriticalThreshold uint64
+	PauseOnCriticalBalance   bool
+	BalanceWebhooks          []Webhook
+
+	// Treasury refills, disabled unless a treasury key is set
+	TreasuryPrivateKeyBytes []byte
+	RefillThreshold         uint64
+	RefillAmount            uint64
+	RefillDailyCap          uint64 // per rolling 24 hours
+
+	// TrustForwardedFor records the client IP of payouts from the
+	// X-Forwarded-For header, only safe behind a proxy that sets it
+	TrustForwardedFor bool
+
+	AdminKeys []AdminKey
+	// AdminDailyBudget caps the payouts sent by admins with SendFunds, 0
+	// disables them
+	AdminDailyBudget uint64 // per rolling 24 hours
+
+	// OpenTelemetry tracing, disabled by default
+	Tracing trace.Config
+
+	// DatabaseBackend selects the Store the faucet persists to
+	DatabaseBackend string
+	// SQLitePath is the database file of the sqlite backend
+	SQLitePath string
 
 	// PostgreSQL configuration
 	PostgresHost     string
@@ -35,88 +145,274 @@ type Config struct {
 	PostgresPassword string
 	PostgresDBName   string
 	PostgresSSLMode  string
-}
 
-func (c *Config) PrivateKey() ed25519.PrivateKey {
-	return ed25519.PrivateKey(c.PrivateKeyBytes)
+	// values are the effective settings the config was loaded from
+	values []Value
+	// networks are the names listed by NETWORKS
+	networks []string
 }
 
-func (c *Config) Address() codec.Address {
-	return auth.NewED25519Address(c.PrivateKey().PublicKey())
+// Validate reports every invalid or inconsistent value of the config
+func (c *Config) Validate() error {
+	errs := c.validateShared()
+	if len(c.Networks) == 0 {
+		errs = append(errs, c.validateNetwork()...)
+	}
+	for _, network := range c.Networks {
+		for _, err := range network.validateNetwork() {
+			errs = append(errs, fmt.Errorf("network %s: %w", network.Network, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func (c *Config) AddressBech32() string {
-	return codec.MustAddressBech32(consts.HRP, c.Address())
+// validateShared checks the settings common to every network
+func (c *Config) validateShared() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.HTTPPort > 0 && c.HTTPPort <= 65535, "PORT must be between 1 and 65535, got %d", c.HTTPPort)
+	check(c.Tracing.TraceSampleRate >= 0 && c.Tracing.TraceSampleRate <= 1, "OTEL_SAMPLE_RATE must be between 0 and 1, got %g", c.Tracing.TraceSampleRate)
+	switch c.DatabaseBackend {
+	case DatabasePostgres:
+		check(c.PostgresPort > 0 && c.PostgresPort <= 65535, "POSTGRES_PORT must be between 1 and 65535, got %d", c.PostgresPort)
+	case DatabaseSQLite:
+		check(c.SQLitePath != "", "SQLITE_PATH is required by the sqlite backend")
+	case DatabaseMemory:
+	default:
+		errs = append(errs, fmt.Errorf("unknown DATABASE_BACKEND %q, must be %s, %s or %s", c.DatabaseBackend, DatabasePostgres, DatabaseSQLite, DatabaseMemory))
+	}
+	return errs
 }
 
-func GetEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// validateNetwork checks the network settings
+func (c *Config) validateNetwork() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
-	return fallback
+	if c.SignerSocket == "" {
+		check(len(c.PrivateKeyBytes) == ed25519.PrivateKeyLen, "PRIVATE_KEY_BYTES must be %d bytes, got %d", ed25519.PrivateKeyLen, len(c.PrivateKeyBytes))
+	}
+	if u, err := url.Parse(c.NuklaiRPC); c.NuklaiRPC == "" {
+		errs = append(errs, errors.New("NUKLAI_RPC is required"))
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("NUKLAI_RPC must be an http or https URL, got %q", c.NuklaiRPC))
+	}
+	check(c.Amount > 0, "AMOUNT must be positive")
+	check(c.StartDifficulty > 0, "START_DIFFICULTY must be positive")
+	check(c.SolutionsPerSalt > 0, "SOLUTIONS_PER_SALT must be positive, got %d", c.SolutionsPerSalt)
+	check(c.TargetDurationPerSalt > 0, "TARGET_DURATION_PER_SALT must be positive, got %d", c.TargetDurationPerSalt)
+	// Salts rotate every TargetDurationPerSalt, a shorter TTL would forget
+	// solutions to a salt that is still accepted
+	check(c.SolutionTTL >= c.TargetDurationPerSalt, "SOLUTION_TTL (%d) must be at least TARGET_DURATION_PER_SALT (%d)", c.SolutionTTL, c.TargetDurationPerSalt)
+	check(c.BalanceCheckInterval > 0, "BALANCE_CHECK_INTERVAL must be positive, got %d", c.BalanceCheckInterval)
+	check(c.BalanceCriticalThreshold <= c.BalanceWarningThreshold, "BALANCE_CRITICAL_THRESHOLD must not exceed BALANCE_WARNING_THRESHOLD")
+	if c.HasTreasury() {
+		check(len(c.TreasuryPrivateKeyBytes) == ed25519.PrivateKeyLen, "TREASURY_PRIVATE_KEY_BYTES must be %d bytes", ed25519.PrivateKeyLen)
+		for _, key := range append([][]byte{c.PrivateKeyBytes}, c.AdditionalPrivateKeys...) {
+			check(!bytes.Equal(c.TreasuryPrivateKeyBytes, key), "TREASURY_PRIVATE_KEY_BYTES must differ from the faucet keys")
+		}
+		check(c.RefillAmount > 0, "REFILL_AMOUNT must be positive")
+	}
+	if !c.DevMode {
+		isDefault := func(key []byte) bool {
+			return base64.StdEncoding.EncodeToString(key) == defaultPrivateKey
+		}
+		check(!isDefault(c.PrivateKeyBytes), "PRIVATE_KEY_BYTES is the well-known default key, set a key of your own or KEYSTORE_FILE, or DEV_MODE=true for local development")
+		for _, key := range c.AdditionalPrivateKeys {
+			check(!isDefault(key), "ADDITIONAL_PRIVATE_KEYS must not contain the well-known default key")
+		}
+		check(!isDefault(c.TreasuryPrivateKeyBytes), "TREASURY_PRIVATE_KEY_BYTES must not be the well-known default key")
+	}
+	return errs
 }
 
-func LoadConfigFromEnv() (*Config, error) {
-	port, err := strconv.Atoi(GetEnv("PORT", "10591"))
-	if err != nil {
-		return nil, err
+// NetworkConfigs returns the config of every network served, c itself unless
+// NETWORKS is set
+func (c *Config) NetworkConfigs() []*Config {
+	if len(c.Networks) == 0 {
+		return []*Config{c}
 	}
+	return c.Networks
+}
 
-	amount, err := strconv.ParseUint(GetEnv("AMOUNT", "100000000"), 10, 64)
-	if err != nil {
-		return nil, err
+// NetworkConfig returns the config of network, nil if it is not served
+func (c *Config) NetworkConfig(network string) *Config {
+	for _, config := range c.NetworkConfigs() {
+		if config.Network == network {
+			return config
+		}
 	}
+	return nil
+}
 
-	startDifficulty, err := strconv.ParseUint(GetEnv("START_DIFFICULTY", "1"), 10, 16)
-	if err != nil {
-		return nil, err
-	}
+func (c *Config) PrivateKey() ed25519.PrivateKey {
+	return ed25519.PrivateKey(c.PrivateKeyBytes)
+}
 
-	solutionsPerSalt, err := strconv.Atoi(GetEnv("SOLUTIONS_PER_SALT", "10"))
-	if err != nil {
-		return nil, err
+// PrivateKeys returns every faucet key, starting with PrivateKey, or nil
+// when the keys are held by a remote signer
+func (c *Config) PrivateKeys() []ed25519.PrivateKey {
+	if len(c.PrivateKeyBytes) == 0 {
+		return nil
 	}
-
-	targetDurationPerSalt, err := strconv.ParseInt(GetEnv("TARGET_DURATION_PER_SALT", "300"), 10, 64)
-	if err != nil {
-		return nil, err
+	keys := []ed25519.PrivateKey{c.PrivateKey()}
+	for _, key := range c.AdditionalPrivateKeys {
+		keys = append(keys, ed25519.PrivateKey(key))
 	}
+	return keys
+}
 
-	privateKeyBytes, err := base64.StdEncoding.DecodeString(GetEnv("PRIVATE_KEY_BYTES", "Mjsdj07tXw2p2pMHGwNPLc6dLSJpLBcvPLJSpk3fr9AbBX3jICl8Ka0MH1ieohaGnPGTjYjJ+9cNZ0gyPb8vpw=="))
-	if err != nil {
-		return nil, err
-	}
+// PostgresDSN returns the connection string of the PostgreSQL database
+func (c *Config) PostgresDSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.PostgresHost, c.PostgresPort, c.PostgresUser, c.PostgresPassword, c.PostgresDBName, c.PostgresSSLMode)
+}
 
-	postgresPort, err := strconv.Atoi(GetEnv("POSTGRES_PORT", "5432"))
-	if err != nil {
-		return nil, err
-	}
+// HasTreasury reports whether a treasury key is configured for refills
+func (c *Config) HasTreasury() bool {
+	return len(c.TreasuryPrivateKeyBytes) > 0
+}
 
-	postgresEnableSSL := GetEnv("POSTGRES_ENABLESSL", "false")
-	postgresSSLMode := "disable"
-	if parsed, err := strconv.ParseBool(postgresEnableSSL); err == nil && parsed {
-		postgresSSLMode = "require"
+func (c *Config) TreasuryPrivateKey() ed25519.PrivateKey {
+	return ed25519.PrivateKey(c.TreasuryPrivateKeyBytes)
+}
+
+func (c *Config) TreasuryAddressBech32() string {
+	return codec.MustAddressBech32(consts.HRP, auth.NewED25519Address(c.TreasuryPrivateKey().PublicKey()))
+}
+
+func GetEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return fallback
+}
 
-	return &Config{
-		HTTPHost: GetEnv("HOST", ""),
-		HTTPPort: port,
+// parseAdminKeys parses a comma separated list of name:role:base64PublicKey
+func parseAdminKeys(value string, faucetKeys []ed25519.PublicKey) ([]AdminKey, error) {
+	var keys []AdminKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid admin key %q: expected name:role:publicKey", entry)
+		}
+		name, role := parts[0], parts[1]
+		if name == "" {
+			return nil, fmt.Errorf("invalid admin key %q: missing name", entry)
+		}
+		if _, ok := adminRoles[role]; !ok {
+			return nil, fmt.Errorf("invalid admin key %q: unknown role %q", name, role)
+		}
+		pkBytes, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin key %q: %w", name, err)
+		}
+		if len(pkBytes) != ed25519.PublicKeyLen {
+			return nil, fmt.Errorf("invalid admin key %q: public key must be %d bytes", name, ed25519.PublicKeyLen)
+		}
+		pk := ed25519.PublicKey(pkBytes)
+		if pk == ed25519.EmptyPublicKey {
+			return nil, fmt.Errorf("invalid admin key %q: empty public key", name)
+		}
+		for _, faucetKey := range faucetKeys {
+			if pk == faucetKey {
+				return nil, fmt.Errorf("invalid admin key %q: a faucet key cannot be used as an admin key", name)
+			}
+		}
+		keys = append(keys, AdminKey{Name: name, Role: role, PublicKey: pk})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("at least one admin key is required")
+	}
+	return keys, nil
+}
 
-		PrivateKeyBytes: privateKeyBytes,
+// parsePrivateKeys parses a comma separated list of base64 private keys that
+// must all differ from each other and from exclude
+func parsePrivateKeys(value string, exclude []byte) ([][]byte, error) {
+	var keys [][]byte
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key %d: %w", i, err)
+		}
+		if len(key) != ed25519.PrivateKeyLen {
+			return nil, fmt.Errorf("invalid private key %d: must be %d bytes", i, ed25519.PrivateKeyLen)
+		}
+		if bytes.Equal(key, exclude) {
+			return nil, fmt.Errorf("invalid private key %d: duplicate key", i)
+		}
+		for _, other := range keys {
+			if bytes.Equal(key, other) {
+				return nil, fmt.Errorf("invalid private key %d: duplicate key", i)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
 
-		NuklaiRPC:             os.Getenv("NUKLAI_RPC"),
-		Amount:                amount,
-		StartDifficulty:       uint16(startDifficulty),
-		SolutionsPerSalt:      solutionsPerSalt,
-		TargetDurationPerSalt: targetDurationPerSalt,
+// parseWebhooks parses a comma separated list of format:url
+func parseWebhooks(value string) ([]Webhook, error) {
+	var webhooks []Webhook
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		format, url, ok := strings.Cut(entry, ":")
+		if !ok || url == "" {
+			return nil, fmt.Errorf("invalid webhook %q: expected format:url", entry)
+		}
+		if format != WebhookJSON && format != WebhookSlack {
+			return nil, fmt.Errorf("invalid webhook %q: unknown format %q", entry, format)
+		}
+		webhooks = append(webhooks, Webhook{Format: format, URL: url})
+	}
+	return webhooks, nil
+}
 
-		AdminToken: GetEnv("ADMIN_TOKEN", "ADMIN_TOKEN"),
+// reservedNetworks are paths served at the root that a network prefix would
+// be confused with
+var reservedNetworks = []string{"faucet", "health", "livez", "readyz", "metrics"}
 
-		PostgresHost:     GetEnv("POSTGRES_HOST", "localhost"),
-		PostgresPort:     postgresPort,
-		PostgresUser:     GetEnv("POSTGRES_USER", "user"),
-		PostgresPassword: GetEnv("POSTGRES_PASSWORD", "password"),
-		PostgresDBName:   GetEnv("POSTGRES_DBNAME", "dbname"),
-		PostgresSSLMode:  postgresSSLMode,
-	}, nil
+// parseNetworks parses a comma separated list of network names, made of
+// lower case letters, digits and dashes
+func parseNetworks(value string) ([]string, error) {
+	var networks []string
+	for _, network := range strings.Split(value, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+		valid := !strings.HasPrefix(network, "-")
+		for _, r := range network {
+			valid = valid && (r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-')
+		}
+		switch {
+		case !valid:
+			return nil, fmt.Errorf("invalid network %q: expected lower case letters, digits and dashes", network)
+		case slices.Contains(reservedNetworks, network):
+			return nil, fmt.Errorf("invalid network %q: reserved path", network)
+		case slices.Contains(networks, network):
+			return nil, fmt.Errorf("duplicate network %q", network)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
 }
@@ -0,0 +1,545 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ava-labs/avalanchego/trace"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nuklai/nuklai-faucet/keystore"
+)
+
+// Sources of a setting, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	// SourceRuntime is a setting changed through the admin RPC
+	SourceRuntime = "runtime"
+)
+
+const redacted = "<redacted>"
+
+// Setting is a configuration key. It is read from the config file as is or
+// in lower case, from the environment as is and from the command line as a
+// flag in lower case with dashes, e.g. NUKLAI_RPC, nuklai_rpc and
+// -nuklai-rpc.
+//
+// When NETWORKS is set, a network setting is overridden for a network by the
+// key prefixed with the upper case network name, e.g. DEVNET_NUKLAI_RPC,
+// from the config file or the environment.
+type Setting struct {
+	Key     string
+	Usage   string
+	Secret  bool // redacted when printed
+	Live    bool // applied by a reload, others require a restart
+	Network bool // may be overridden per network
+}
+
+// Settings lists every configuration key
+var Settings = []Setting{
+	{Key: "HOST", Usage: "HTTP host to bind to, empty for all interfaces"},
+	{Key: "PORT", Usage: "HTTP port"},
+	{Key: "NETWORKS", Usage: "comma separated networks served under /<network>, each overriding the network settings with <NETWORK>_ prefixed keys"},
+	{Key: "PRIVATE_KEY_BYTES", Usage: "base64 private key of the primary faucet wallet", Secret: true, Network: true},
+	{Key: "KEYSTORE_FILE", Usage: "encrypted keystore of the primary faucet wallet, replaces PRIVATE_KEY_BYTES", Network: true},
+	{Key: "KEYSTORE_PASSPHRASE", Usage: "passphrase of KEYSTORE_FILE", Secret: true, Network: true},
+	{Key: "SIGNER_SOCKET", Usage: "Unix socket of a remote signer holding the faucet keys, replaces PRIVATE_KEY_BYTES", Network: true},
+	{Key: "ADDITIONAL_PRIVATE_KEYS", Usage: "comma separated base64 private keys of additional faucet wallets", Secret: true, Network: true},
+	{Key: "NUKLAI_RPC", Usage: "Nuklai RPC endpoint", Network: true},
+	{Key: "AMOUNT", Usage: "amount of each payout", Live: true, Network: true},
+	{Key: "MIN_BALANCE", Usage: "balance below which the faucet reports not ready", Live: true, Network: true},
+	{Key: "START_DIFFICULTY", Usage: "difficulty of the challenge", Live: true, Network: true},
+	{Key: "SOLUTIONS_PER_SALT", Usage: "solutions accepted before the salt rotates", Live: true, Network: true},
+	{Key: "TARGET_DURATION_PER_SALT", Usage: "seconds before the salt rotates", Live: true, Network: true},
+	{Key: "SOLUTION_TTL", Usage: "seconds used solutions are remembered to reject replays", Live: true, Network: true},
+	{Key: "REQUIRE_OWNERSHIP_PROOF", Usage: "require solutions to be signed by the key of the destination address", Live: true, Network: true},
+	{Key: "BALANCE_CHECK_INTERVAL", Usage: "seconds between balance checks", Live: true, Network: true},
+	{Key: "BALANCE_WARNING_THRESHOLD", Usage: "balance below which a warning alert is sent", Live: true, Network: true},
+	{Key: "BALANCE_CRITICAL_THRESHOLD", Usage: "balance below which a critical alert is sent", Live: true, Network: true},
+	{Key: "PAUSE_ON_CRITICAL_BALANCE", Usage: "pause payouts at the critical balance level", Live: true, Network: true},
+	{Key: "BALANCE_WEBHOOKS", Usage: "comma separated format:url balance alert webhooks", Secret: true, Live: true, Network: true},
+	{Key: "TREASURY_PRIVATE_KEY_BYTES", Usage: "base64 private key of the treasury refilling the faucet", Secret: true, Network: true},
+	{Key: "REFILL_THRESHOLD", Usage: "balance below which the faucet is refilled", Live: true, Network: true},
+	{Key: "REFILL_AMOUNT", Usage: "amount of each refill", Live: true, Network: true},
+	{Key: "REFILL_DAILY_CAP", Usage: "maximum amount refilled per rolling 24 hours", Live: true, Network: true},
+	{Key: "TRUST_FORWARDED_FOR", Usage: "record the client IP of payouts from X-Forwarded-For", Live: true},
+	{Key: "DEV_MODE", Usage: "allow the well-known default private key, for local development only"},
+	{Key: "ADMIN_KEYS", Usage: "comma separated name:role:base64PublicKey admin keys", Live: true},
+	{Key: "ADMIN_DAILY_BUDGET", Usage: "maximum amount sent by admins with sendFunds per rolling 24 hours, 0 to disable it", Live: true, Network: true},
+	{Key: "OTEL_ENABLED", Usage: "export traces"},
+	{Key: "OTEL_EXPORTER", Usage: "trace exporter, grpc or http"},
+	{Key: "OTEL_ENDPOINT", Usage: "trace collector endpoint"},
+	{Key: "OTEL_INSECURE", Usage: "disable TLS to the trace collector"},
+	{Key: "OTEL_SAMPLE_RATE", Usage: "fraction of traces to sample"},
+	{Key: "DATABASE_BACKEND", Usage: "database backend, postgres, sqlite or memory"},
+	{Key: "SQLITE_PATH", Usage: "database file of the sqlite backend"},
+	{Key: "POSTGRES_HOST", Usage: "PostgreSQL host"},
+	{Key: "POSTGRES_PORT", Usage: "PostgreSQL port"},
+	{Key: "POSTGRES_USER", Usage: "PostgreSQL user"},
+	{Key: "POSTGRES_PASSWORD", Usage: "PostgreSQL password", Secret: true},
+	{Key: "POSTGRES_DBNAME", Usage: "PostgreSQL database name"},
+	{Key: "POSTGRES_ENABLESSL", Usage: "require SSL to PostgreSQL"},
+}
+
+func lookupSetting(key string) Setting {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting
+		}
+	}
+	panic("unknown setting " + key)
+}
+
+// flagName returns the command-line flag of key
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// NetworkKey returns the key overriding the setting key for network
+func NetworkKey(network, key string) string {
+	return strings.ToUpper(strings.ReplaceAll(network, "-", "_")) + "_" + key
+}
+
+// Value is the effective value of a setting and where it came from
+type Value struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Network is set if the value overrides the setting for that network
+	Network string `json:"network,omitempty"`
+}
+
+// Flags binds -config and a command-line flag for every setting to a flag
+// set
+type Flags struct {
+	fs     *flag.FlagSet
+	path   *string
+	values map[string]*string
+}
+
+// RegisterFlags registers -config and a flag for every setting on fs
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{
+		fs:     fs,
+		path:   fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)"),
+		values: map[string]*string{},
+	}
+	for _, setting := range Settings {
+		f.values[setting.Key] = fs.String(flagName(setting.Key), "", setting.Usage)
+	}
+	return f
+}
+
+// Load loads the config once the flag set is parsed. Settings are read from,
+// in increasing order of precedence, the config file, the environment and
+// the flags that were set. It can be called again to reload the config file.
+func (f *Flags) Load() (*Config, error) {
+	set := map[string]string{}
+	f.fs.Visit(func(fl *flag.Flag) {
+		for key, value := range f.values {
+			if flagName(key) == fl.Name {
+				set[key] = *value
+			}
+		}
+	})
+	return Load(*f.path, set)
+}
+
+// Path returns the config file, empty if none is used
+func (f *Flags) Path() string {
+	return *f.path
+}
+
+// LoadConfigFromEnv loads the config from the environment only
+func LoadConfigFromEnv() (*Config, error) {
+	return Load("", nil)
+}
+
+// Load loads the config from the YAML or TOML file at path if not empty, then
+// the environment, then flags, which maps setting keys to values. The config
+// is validated and every problem found is reported at once.
+func Load(path string, flags map[string]string) (*Config, error) {
+	l := &loader{flags: flags}
+	if path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+	}
+	c := l.load()
+	// Each network reports the errors of its network settings, and those of
+	// the shared settings that depend on the network
+	shared := len(c.networks) > 0
+	errs := l.errors(func(e *settingError) bool { return !shared || !lookupSetting(e.key).Network })
+	for _, network := range c.networks {
+		nl := &loader{file: l.file, flags: flags, network: network}
+		n := nl.load()
+		n.Network = network
+		c.Networks = append(c.Networks, n)
+		errs = append(errs, nl.errors(func(e *settingError) bool {
+			return lookupSetting(e.key).Network || !slices.ContainsFunc(l.errs, e.same)
+		})...)
+	}
+	for key := range l.file {
+		if !isFileKey(key, c.networks) {
+			errs = append(errs, fmt.Errorf("unknown setting %q in config file %s", strings.ToLower(key), path))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile reads a flat YAML or TOML config file, selected by the extension
+// of path. Lists are joined with commas.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+	var raw map[string]any
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	var errs []error
+	file := map[string]string{}
+	for name, value := range raw {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		switch v := value.(type) {
+		case map[string]any:
+			errs = append(errs, fmt.Errorf("setting %q in config file %s must be a value or a list", name, path))
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			file[key] = strings.Join(items, ",")
+		case nil:
+			file[key] = ""
+		default:
+			file[key] = fmt.Sprint(v)
+		}
+	}
+	return file, errors.Join(errs...)
+}
+
+// isFileKey reports whether key of a config file is a setting or overrides a
+// network setting for one of networks
+func isFileKey(key string, networks []string) bool {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return true
+		}
+		for _, network := range networks {
+			if setting.Network && NetworkKey(network, setting.Key) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loader resolves settings and collects the errors of those that do not
+// parse
+type loader struct {
+	file  map[string]string
+	flags map[string]string
+	// network applies the overrides of that network when set
+	network string
+	// fixed overrides every other source when set, see Config.Reload
+	fixed  map[string]Value
+	values []Value
+	errs   []*settingError
+}
+
+// settingError is a setting that does not parse
+type settingError struct {
+	key     string
+	network string
+	err     error
+}
+
+func (e *settingError) Error() string {
+	if e.network != "" {
+		return fmt.Sprintf("invalid %s of network %s: %v", e.key, e.network, e.err)
+	}
+	return fmt.Sprintf("invalid %s: %v", e.key, e.err)
+}
+
+func (e *settingError) Unwrap() error {
+	return e.err
+}
+
+// same reports whether e and other are the same error, whatever the network
+func (e *settingError) same(other *settingError) bool {
+	return e.key == other.key && e.err.Error() == other.err.Error()
+}
+
+func (l *loader) get(key, fallback string) string {
+	setting := lookupSetting(key)
+	if l.fixed != nil {
+		value := l.fixed[key]
+		l.values = append(l.values, value)
+		return value.Value
+	}
+	value := Value{Key: key, Value: fallback, Source: SourceDefault}
+	if v, ok := l.file[key]; ok {
+		value.Value, value.Source = v, SourceFile
+	}
+	if v, ok := os.LookupEnv(key); ok {
+		value.Value, value.Source = v, SourceEnv
+	}
+	if v, ok := l.flags[key]; ok {
+		value.Value, value.Source = v, SourceFlag
+	}
+	if l.network != "" && setting.Network {
+		override := NetworkKey(l.network, key)
+		if v, ok := l.file[override]; ok {
+			value = Value{Key: key, Value: v, Source: SourceFile, Network: l.network}
+		}
+		if v, ok := os.LookupEnv(override); ok {
+			value = Value{Key: key, Value: v, Source: SourceEnv, Network: l.network}
+		}
+	}
+	l.values = append(l.values, value)
+	return value.Value
+}
+
+// source returns where the setting key, already resolved, came from
+func (l *loader) source(key string) string {
+	for _, value := range l.values {
+		if value.Key == key {
+			return value.Source
+		}
+	}
+	return SourceDefault
+}
+
+func (l *loader) fail(key string, err error) {
+	l.errs = append(l.errs, &settingError{key: key, network: l.network, err: err})
+}
+
+// errors returns the errors selected by keep
+func (l *loader) errors(keep func(*settingError) bool) []error {
+	var errs []error
+	for _, err := range l.errs {
+		if keep(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (l *loader) int(key string, fallback int) int {
+	v, err := strconv.Atoi(l.get(key, strconv.Itoa(fallback)))
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) int64(key string, fallback int64) int64 {
+	v, err := strconv.ParseInt(l.get(key, strconv.FormatInt(fallback, 10)), 10, 64)
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) uint64(key string, fallback uint64) uint64 {
+	v, err := strconv.ParseUint(l.get(key, strconv.FormatUint(fallback, 10)), 10, 64)
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) uint16(key string, fallback uint16) uint16 {
+	v, err := strconv.ParseUint(l.get(key, strconv.FormatUint(uint64(fallback), 10)), 10, 16)
+	if err != nil {
+		l.fail(key, err)
+	}
+	return uint16(v)
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(l.get(key, strconv.FormatBool(fallback)))
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) float64(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(l.get(key, strconv.FormatFloat(fallback, 'g', -1, 64)), 64)
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) base64(key, fallback string) []byte {
+	v, err := base64.StdEncoding.DecodeString(l.get(key, fallback))
+	if err != nil {
+		l.fail(key, err)
+	}
+	return v
+}
+
+func (l *loader) load() *Config {
+	networks, err := parseNetworks(l.get("NETWORKS", ""))
+	if err != nil {
+		l.fail("NETWORKS", err)
+	}
+
+	c := &Config{
+		HTTPHost: l.get("HOST", ""),
+		HTTPPort: l.int("PORT", 10591),
+
+		PrivateKeyBytes: l.base64("PRIVATE_KEY_BYTES", defaultPrivateKey),
+		KeystoreFile:    l.get("KEYSTORE_FILE", ""),
+		SignerSocket:    l.get("SIGNER_SOCKET", ""),
+		DevMode:         l.bool("DEV_MODE", false),
+
+		NuklaiRPC:             l.get("NUKLAI_RPC", ""),
+		Amount:                l.uint64("AMOUNT", 100000000),
+		StartDifficulty:       l.uint16("START_DIFFICULTY", 1),
+		SolutionsPerSalt:      l.int("SOLUTIONS_PER_SALT", 10),
+		TargetDurationPerSalt: l.int64("TARGET_DURATION_PER_SALT", 300),
+		SolutionTTL:           l.int64("SOLUTION_TTL", 86400),
+		RequireOwnershipProof: l.bool("REQUIRE_OWNERSHIP_PROOF", false),
+
+		BalanceCheckInterval:   l.int64("BALANCE_CHECK_INTERVAL", 60),
+		PauseOnCriticalBalance: l.bool("PAUSE_ON_CRITICAL_BALANCE", false),
+
+		TreasuryPrivateKeyBytes: l.base64("TREASURY_PRIVATE_KEY_BYTES", ""),
+
+		TrustForwardedFor: l.bool("TRUST_FORWARDED_FOR", false),
+
+		AdminDailyBudget: l.uint64("ADMIN_DAILY_BUDGET", 0),
+
+		Tracing: trace.Config{
+			ExporterConfig: trace.ExporterConfig{
+				Endpoint: l.get("OTEL_ENDPOINT", "localhost:4317"),
+				Insecure: l.bool("OTEL_INSECURE", true),
+			},
+			Enabled:         l.bool("OTEL_ENABLED", false),
+			TraceSampleRate: l.float64("OTEL_SAMPLE_RATE", 1),
+			AppName:         "nuklai-faucet",
+		},
+
+		DatabaseBackend: l.get("DATABASE_BACKEND", DatabasePostgres),
+		SQLitePath:      l.get("SQLITE_PATH", "faucet.db"),
+
+		PostgresHost:     l.get("POSTGRES_HOST", "localhost"),
+		PostgresPort:     l.int("POSTGRES_PORT", 5432),
+		PostgresUser:     l.get("POSTGRES_USER", "user"),
+		PostgresPassword: l.get("POSTGRES_PASSWORD", "password"),
+		PostgresDBName:   l.get("POSTGRES_DBNAME", "dbname"),
+		PostgresSSLMode:  "disable",
+
+		networks: networks,
+	}
+
+	passphrase := l.get("KEYSTORE_PASSPHRASE", "")
+	if c.SignerSocket != "" {
+		// The faucet holds no key of its own
+		if l.source("PRIVATE_KEY_BYTES") != SourceDefault || c.KeystoreFile != "" {
+			l.fail("SIGNER_SOCKET", errors.New("cannot be set along with PRIVATE_KEY_BYTES or KEYSTORE_FILE"))
+		}
+		c.PrivateKeyBytes = nil
+	} else if c.KeystoreFile != "" {
+		if l.source("PRIVATE_KEY_BYTES") != SourceDefault {
+			l.fail("KEYSTORE_FILE", errors.New("cannot be set along with PRIVATE_KEY_BYTES"))
+		} else if key, err := keystore.ReadFile(c.KeystoreFile, []byte(passphrase)); err != nil {
+			l.fail("KEYSTORE_FILE", err)
+		} else {
+			c.PrivateKeyBytes = key[:]
+		}
+	}
+
+	// Defaults derived from other settings
+	c.MinBalance = l.uint64("MIN_BALANCE", c.Amount)
+	c.BalanceWarningThreshold = l.uint64("BALANCE_WARNING_THRESHOLD", 100*c.Amount)
+	c.BalanceCriticalThreshold = l.uint64("BALANCE_CRITICAL_THRESHOLD", 10*c.Amount)
+	c.RefillThreshold = l.uint64("REFILL_THRESHOLD", c.BalanceWarningThreshold)
+	c.RefillAmount = l.uint64("REFILL_AMOUNT", c.BalanceWarningThreshold)
+	c.RefillDailyCap = l.uint64("REFILL_DAILY_CAP", 5*c.RefillAmount)
+
+	if c.BalanceWebhooks, err = parseWebhooks(l.get("BALANCE_WEBHOOKS", "")); err != nil {
+		l.fail("BALANCE_WEBHOOKS", err)
+	}
+	if c.AdditionalPrivateKeys, err = parsePrivateKeys(l.get("ADDITIONAL_PRIVATE_KEYS", ""), c.PrivateKeyBytes); err != nil {
+		l.fail("ADDITIONAL_PRIVATE_KEYS", err)
+	} else if c.SignerSocket != "" && len(c.AdditionalPrivateKeys) > 0 {
+		l.fail("ADDITIONAL_PRIVATE_KEYS", errors.New("cannot be set along with SIGNER_SOCKET, the signer holds every faucet key"))
+	}
+	var faucetKeys []ed25519.PublicKey
+	for _, key := range append([][]byte{c.PrivateKeyBytes}, c.AdditionalPrivateKeys...) {
+		if len(key) == ed25519.PrivateKeyLen {
+			faucetKeys = append(faucetKeys, ed25519.PrivateKey(key).PublicKey())
+		}
+	}
+	if c.AdminKeys, err = parseAdminKeys(l.get("ADMIN_KEYS", ""), faucetKeys); err != nil {
+		l.fail("ADMIN_KEYS", err)
+	}
+	if c.Tracing.Type, err = trace.ExporterTypeFromString(l.get("OTEL_EXPORTER", "grpc")); err != nil {
+		l.fail("OTEL_EXPORTER", err)
+	}
+	if l.bool("POSTGRES_ENABLESSL", false) {
+		c.PostgresSSLMode = "require"
+	}
+
+	c.values = l.values
+	return c
+}
+
+// Values returns the effective value of every setting, in the order of
+// Settings, with secrets redacted
+func (c *Config) Values() []Value {
+	byKey := map[string]Value{}
+	for _, value := range c.values {
+		byKey[value.Key] = value
+	}
+	var values []Value
+	for _, setting := range Settings {
+		value, ok := byKey[setting.Key]
+		if !ok {
+			continue
+		}
+		if setting.Secret && value.Value != "" {
+			value.Value = redacted
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package config
+
+import (
+	"errors"
+	"slices"
+)
+
+// Reload merges next, freshly loaded, into the running config c. Live
+// settings are taken from next and the others are kept from c. It returns
+// the merged config along with the keys of the live settings that changed,
+// which are applied, and the keys of the other settings that changed, which
+// are rejected until a restart.
+func (c *Config) Reload(next *Config) (*Config, []string, []string, error) {
+	running := map[string]Value{}
+	for _, value := range c.values {
+		running[value.Key] = value
+	}
+	var (
+		applied  []string
+		rejected []string
+		fixed    = map[string]Value{}
+	)
+	for _, value := range next.values {
+		current, ok := running[value.Key]
+		switch {
+		case ok && (current.Value == value.Value || current.Source == SourceRuntime):
+			// Changes made through the admin RPC are kept
+			fixed[value.Key] = current
+		case lookupSetting(value.Key).Live:
+			fixed[value.Key] = value
+			applied = append(applied, value.Key)
+		default:
+			fixed[value.Key] = current
+			rejected = append(rejected, value.Key)
+		}
+	}
+
+	l := &loader{network: c.Network, fixed: fixed}
+	merged := l.load()
+	merged.Network = c.Network
+	if err := errors.Join(l.errors(func(*settingError) bool { return true })...); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := merged.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	return merged, applied, rejected, nil
+}
+
+// WithNuklaiRPC returns a copy of c using the Nuklai RPC endpoint uri
+func (c *Config) WithNuklaiRPC(uri string) *Config {
+	config := *c
+	config.NuklaiRPC = uri
+	config.values = slices.Clone(c.values)
+	for i := range config.values {
+		if config.values[i].Key == "NUKLAI_RPC" {
+			config.values[i] = Value{Key: "NUKLAI_RPC", Value: uri, Source: SourceRuntime, Network: c.Network}
+		}
+	}
+	return &config
+}
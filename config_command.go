@@ -0,0 +1,55 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	fconfig "github.com/nuklai/nuklai-faucet/config"
+)
+
+const configUsage = `Usage: nuklai-faucet [flags] config <command>
+
+Commands:
+  print   print the effective config and the source of each setting, with
+          secrets redacted, followed by the overrides of each network
+`
+
+// runConfig implements the config subcommand, which inspects the config
+// without starting the faucet
+func runConfig(config *fconfig.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return errors.New("missing config command")
+	}
+	if args[0] != "print" || len(args) > 1 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return fmt.Errorf("unknown config command %q", strings.Join(args, " "))
+	}
+
+	values := config.Values()
+	for _, network := range config.Networks {
+		for _, value := range network.Values() {
+			if value.Network != "" {
+				values = append(values, value)
+			}
+		}
+	}
+
+	// The output is a valid YAML config file
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, value := range values {
+		key := value.Key
+		if value.Network != "" {
+			key = fconfig.NetworkKey(value.Network, key)
+		}
+		fmt.Fprintf(w, "%s: %s\t# %s\n", strings.ToLower(key), strconv.Quote(value.Value), value.Source)
+	}
+	return w.Flush()
+}
@@ -4,36 +4,48 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/ava-labs/avalanchego/trace"
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-type DB struct {
-	conn *sql.DB
-}
+// Dialect is the SQL database a DB is backed by. Its value is also the name of
+// the database/sql driver.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+var _ Store = (*DB)(nil)
 
-type Transaction struct {
-	TxID        string `json:"txID"`
-	Destination string `json:"destination"`
-	Amount      uint64 `json:"amount"`
-	Timestamp   int64  `json:"timestamp"`
+// DB is a Store backed by PostgreSQL or SQLite. Queries are written in the
+// subset of SQL both understand and differences in the schema are handled by
+// the migrations of each dialect.
+type DB struct {
+	conn    *sql.DB
+	dialect Dialect
+	tracer  trace.Tracer
 }
 
-func NewDB(conn *sql.DB) (*DB, error) {
-	db := &DB{conn: conn}
+// NewDB applies any pending migration before returning
+func NewDB(conn *sql.DB, dialect Dialect, tracer trace.Tracer) (*DB, error) {
+	db := &DB{conn: conn, dialect: dialect, tracer: tracer}
 
-	query := `CREATE TABLE IF NOT EXISTS transactions (
-        txid TEXT PRIMARY KEY,
-        destination TEXT,
-        amount BIGINT,
-        timestamp BIGINT
-    )`
-	_, err := db.conn.Exec(query)
+	migrator, err := NewMigrator(conn, dialect)
 	if err != nil {
-		log.Printf("Error creating table: %v", err)
+		return nil, err
+	}
+	if _, err := migrator.Up(context.Background(), 0); err != nil {
+		log.Printf("Error migrating database: %v", err)
 		return nil, err
 	}
 
@@ -41,22 +53,44 @@ func NewDB(conn *sql.DB) (*DB, error) {
 	return db, nil
 }
 
-func (db *DB) SaveTransaction(txID, destination string, amount uint64) error {
-	timestamp := time.Now().Unix()
-	log.Printf("Saving transaction: txID=%s, destination=%s, amount=%d, timestamp=%d", txID, destination, amount, timestamp)
-	query := `INSERT INTO transactions (txid, destination, amount, timestamp) VALUES ($1, $2, $3, $4)`
-	_, err := db.conn.Exec(query, txID, destination, amount, timestamp)
+// transactionColumns are the columns of the transactions table, in the
+// order scanTransaction reads them
+const transactionColumns = `txid, chain_id, destination, amount, asset, api_key_id, admin, reason, timestamp, fee, difficulty, salt_id, solution_hash,
+        client_ip, user_agent, rpc_endpoint, processing_time, unconfirmed`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTransaction(row scanner) (Transaction, error) {
+	var txn Transaction
+	err := row.Scan(&txn.TxID, &txn.ChainID, &txn.Destination, &txn.Amount, &txn.Asset, &txn.APIKeyID, &txn.Admin, &txn.Reason, &txn.Timestamp, &txn.Fee, &txn.Difficulty, &txn.SaltID, &txn.SolutionHash,
+		&txn.ClientIP, &txn.UserAgent, &txn.RPCEndpoint, &txn.ProcessingTime, &txn.Unconfirmed)
+	return txn, err
+}
+
+func (db *DB) SaveTransaction(ctx context.Context, txn *Transaction) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SaveTransaction")
+	defer span.End()
+
+	txn.Timestamp = time.Now().Unix()
+	log.Printf("Saving transaction: txID=%s, chainID=%s, destination=%s, amount=%d, fee=%d, timestamp=%d", txn.TxID, txn.ChainID, txn.Destination, txn.Amount, txn.Fee, txn.Timestamp)
+	query := `INSERT INTO transactions (` + transactionColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+	_, err := db.conn.ExecContext(ctx, query, txn.TxID, txn.ChainID, txn.Destination, txn.Amount, txn.Asset, txn.APIKeyID, txn.Admin, txn.Reason, txn.Timestamp, txn.Fee, txn.Difficulty, txn.SaltID, txn.SolutionHash,
+		txn.ClientIP, txn.UserAgent, txn.RPCEndpoint, txn.ProcessingTime, txn.Unconfirmed)
 	if err != nil {
 		log.Printf("Error saving transaction: %v", err)
 	}
 	return err
 }
 
-func (db *DB) GetTransaction(txID string) (*Transaction, error) {
-	var txn Transaction
-	query := `SELECT txid, destination, amount, timestamp FROM transactions WHERE txid = $1`
-	row := db.conn.QueryRow(query, txID)
-	err := row.Scan(&txn.TxID, &txn.Destination, &txn.Amount, &txn.Timestamp)
+func (db *DB) GetTransaction(ctx context.Context, txID string) (*Transaction, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetTransaction")
+	defer span.End()
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE txid = $1`
+	txn, err := scanTransaction(db.conn.QueryRowContext(ctx, query, txID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("No transaction found with txID: %s", txID)
@@ -68,19 +102,109 @@ func (db *DB) GetTransaction(txID string) (*Transaction, error) {
 	return &txn, nil
 }
 
-func (db *DB) GetAllTransactions() ([]Transaction, error) {
+func (db *DB) GetAllTransactions(ctx context.Context) ([]Transaction, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAllTransactions")
+	defer span.End()
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	return db.queryTransactions(ctx, query)
+}
+
+// SearchTransactions returns the transactions matching filter, the most
+// recent first
+func (db *DB) SearchTransactions(ctx context.Context, filter *TransactionFilter) ([]Transaction, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.SearchTransactions")
+	defer span.End()
+
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.TxID != "" {
+		add("txid = $%d", filter.TxID)
+	}
+	if filter.ChainID != "" {
+		add("chain_id = $%d", filter.ChainID)
+	}
+	if filter.Destination != "" {
+		add("destination = $%d", filter.Destination)
+	}
+	if filter.APIKeyID != "" {
+		add("api_key_id = $%d", filter.APIKeyID)
+	}
+	if filter.Admin != "" {
+		add("admin = $%d", filter.Admin)
+	}
+	if filter.Difficulty != 0 {
+		add("difficulty = $%d", filter.Difficulty)
+	}
+	if filter.SaltID != "" {
+		add("salt_id = $%d", filter.SaltID)
+	}
+	if filter.SolutionHash != "" {
+		add("solution_hash = $%d", filter.SolutionHash)
+	}
+	if filter.ClientIP != "" {
+		add("client_ip = $%d", filter.ClientIP)
+	}
+	if filter.UserAgent != "" {
+		// LIKE is case-insensitive in SQLite but not in PostgreSQL, lower
+		// both sides so the backends agree
+		add(`LOWER(user_agent) LIKE $%d ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(filter.UserAgent))+"%")
+	}
+	if filter.RPCEndpoint != "" {
+		add("rpc_endpoint = $%d", filter.RPCEndpoint)
+	}
+	if filter.MinFee != 0 {
+		add("fee >= $%d", filter.MinFee)
+	}
+	if filter.MaxFee != 0 {
+		add("fee <= $%d", filter.MaxFee)
+	}
+	if filter.MinProcessingTime != 0 {
+		add("processing_time >= $%d", filter.MinProcessingTime)
+	}
+	if filter.MaxProcessingTime != 0 {
+		add("processing_time <= $%d", filter.MaxProcessingTime)
+	}
+	if filter.Since != 0 {
+		add("timestamp >= $%d", filter.Since)
+	}
+	if filter.Until != 0 {
+		add("timestamp <= $%d", filter.Until)
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY timestamp DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	return db.queryTransactions(ctx, query, args...)
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (db *DB) queryTransactions(ctx context.Context, query string, args ...any) ([]Transaction, error) {
 	var transactions []Transaction
-	query := `SELECT txid, destination, amount, timestamp FROM transactions`
-	rows, err := db.conn.Query(query)
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error fetching all transactions: %v", err)
+		log.Printf("Error fetching transactions: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var txn Transaction
-		if err := rows.Scan(&txn.TxID, &txn.Destination, &txn.Amount, &txn.Timestamp); err != nil {
+		txn, err := scanTransaction(rows)
+		if err != nil {
 			log.Printf("Error scanning transaction row: %v", err)
 			return nil, err
 		}
@@ -95,6 +219,702 @@ func (db *DB) GetAllTransactions() ([]Transaction, error) {
 	return transactions, nil
 }
 
+// GetStats aggregates every payout of the native asset recorded on chainID, or
+// on every chain if empty, and those of the last 24 hours
+func (db *DB) GetStats(ctx context.Context, chainID string) (*Stats, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetStats")
+	defer span.End()
+
+	var stats Stats
+	query := `SELECT COUNT(*), COALESCE(SUM(amount), 0), COUNT(DISTINCT destination), COALESCE(MAX(timestamp), 0) FROM transactions
+        WHERE ($1 = '' OR chain_id = $1) AND asset = ''`
+	row := db.conn.QueryRowContext(ctx, query, chainID)
+	if err := row.Scan(&stats.TotalTransactions, &stats.TotalAmount, &stats.UniqueDestinations, &stats.LastTimestamp); err != nil {
+		log.Printf("Error fetching stats: %v", err)
+		return nil, err
+	}
+	query = `SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM transactions WHERE ($1 = '' OR chain_id = $1) AND asset = '' AND timestamp >= $2`
+	row = db.conn.QueryRowContext(ctx, query, chainID, time.Now().Add(-24*time.Hour).Unix())
+	if err := row.Scan(&stats.Last24hTransactions, &stats.Last24hAmount); err != nil {
+		log.Printf("Error fetching stats: %v", err)
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// AddDeniedAddress adds address to the deny list, updating the reason if it
+// is already denied
+func (db *DB) AddDeniedAddress(ctx context.Context, address, reason string) error {
+	ctx, span := db.tracer.Start(ctx, "DB.AddDeniedAddress")
+	defer span.End()
+
+	timestamp := time.Now().Unix()
+	log.Printf("Denying address: address=%s, reason=%q", address, reason)
+	query := `INSERT INTO deny_list (address, reason, timestamp) VALUES ($1, $2, $3)
+        ON CONFLICT (address) DO UPDATE SET reason = EXCLUDED.reason, timestamp = EXCLUDED.timestamp`
+	_, err := db.conn.ExecContext(ctx, query, address, reason, timestamp)
+	if err != nil {
+		log.Printf("Error denying address: %v", err)
+	}
+	return err
+}
+
+// RemoveDeniedAddress removes address from the deny list
+func (db *DB) RemoveDeniedAddress(ctx context.Context, address string) error {
+	ctx, span := db.tracer.Start(ctx, "DB.RemoveDeniedAddress")
+	defer span.End()
+
+	log.Printf("Removing denied address: address=%s", address)
+	query := `DELETE FROM deny_list WHERE address = $1`
+	_, err := db.conn.ExecContext(ctx, query, address)
+	if err != nil {
+		log.Printf("Error removing denied address: %v", err)
+	}
+	return err
+}
+
+func (db *DB) GetDeniedAddresses(ctx context.Context) ([]DeniedAddress, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetDeniedAddresses")
+	defer span.End()
+
+	var denied []DeniedAddress
+	query := `SELECT address, reason, timestamp FROM deny_list ORDER BY timestamp DESC`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		log.Printf("Error fetching denied addresses: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d DeniedAddress
+		if err := rows.Scan(&d.Address, &d.Reason, &d.Timestamp); err != nil {
+			log.Printf("Error scanning denied address row: %v", err)
+			return nil, err
+		}
+		denied = append(denied, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+
+	return denied, nil
+}
+
+// SavePauseState persists the maintenance state of chainID so it survives
+// restarts
+func (db *DB) SavePauseState(ctx context.Context, chainID string, state *PauseState) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SavePauseState")
+	defer span.End()
+
+	state.UpdatedAt = time.Now().Unix()
+	log.Printf("Saving pause state: chainID=%s, paused=%t, message=%q, resumeAt=%d", chainID, state.Paused, state.Message, state.ResumeAt)
+	query := `INSERT INTO pause_states (chain_id, paused, message, resume_at, updated_at) VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (chain_id) DO UPDATE SET paused = EXCLUDED.paused, message = EXCLUDED.message, resume_at = EXCLUDED.resume_at, updated_at = EXCLUDED.updated_at`
+	_, err := db.conn.ExecContext(ctx, query, chainID, state.Paused, state.Message, state.ResumeAt, state.UpdatedAt)
+	if err != nil {
+		log.Printf("Error saving pause state: %v", err)
+	}
+	return err
+}
+
+// GetPauseState returns the persisted maintenance state of chainID, or an
+// unpaused state if none was ever saved
+func (db *DB) GetPauseState(ctx context.Context, chainID string) (*PauseState, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetPauseState")
+	defer span.End()
+
+	var state PauseState
+	query := `SELECT paused, message, resume_at, updated_at FROM pause_states WHERE chain_id = $1`
+	row := db.conn.QueryRowContext(ctx, query, chainID)
+	err := row.Scan(&state.Paused, &state.Message, &state.ResumeAt, &state.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &state, nil
+	}
+	if err != nil {
+		log.Printf("Error fetching pause state: %v", err)
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveRefill records a refill attempt
+func (db *DB) SaveRefill(ctx context.Context, refill *Refill) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SaveRefill")
+	defer span.End()
+
+	refill.Timestamp = time.Now().Unix()
+	log.Printf("Saving refill: txID=%s, chainID=%s, amount=%d, status=%s, timestamp=%d", refill.TxID, refill.ChainID, refill.Amount, refill.Status, refill.Timestamp)
+	query := `INSERT INTO refills (txid, chain_id, amount, status, error, timestamp) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := db.conn.ExecContext(ctx, query, refill.TxID, refill.ChainID, refill.Amount, refill.Status, refill.Error, refill.Timestamp)
+	if err != nil {
+		log.Printf("Error saving refill: %v", err)
+	}
+	return err
+}
+
+// GetRefilledSince returns the amount of the successful refills on chainID
+// since the given unix time
+func (db *DB) GetRefilledSince(ctx context.Context, chainID string, since int64) (uint64, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetRefilledSince")
+	defer span.End()
+
+	var amount uint64
+	query := `SELECT COALESCE(SUM(amount), 0) FROM refills WHERE chain_id = $1 AND status <> $2 AND timestamp >= $3`
+	row := db.conn.QueryRowContext(ctx, query, chainID, RefillFailed, since)
+	if err := row.Scan(&amount); err != nil {
+		log.Printf("Error fetching refilled amount: %v", err)
+		return 0, err
+	}
+	return amount, nil
+}
+
+// GetAdminPaidSince returns the amount of the native asset sent by admins
+// on chainID since the given unix time, unconfirmed payouts included
+func (db *DB) GetAdminPaidSince(ctx context.Context, chainID string, since int64) (uint64, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAdminPaidSince")
+	defer span.End()
+
+	var amount uint64
+	query := `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE chain_id = $1 AND admin <> '' AND asset = '' AND timestamp >= $2`
+	row := db.conn.QueryRowContext(ctx, query, chainID, since)
+	if err := row.Scan(&amount); err != nil {
+		log.Printf("Error fetching admin paid amount: %v", err)
+		return 0, err
+	}
+	return amount, nil
+}
+
+// GetRefillStats aggregates every refill attempt recorded on chainID
+func (db *DB) GetRefillStats(ctx context.Context, chainID string) (*RefillStats, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetRefillStats")
+	defer span.End()
+
+	var stats RefillStats
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE status = $1), COUNT(*) FILTER (WHERE status = $2),
+        COALESCE(SUM(amount) FILTER (WHERE status = $3), 0),
+        COALESCE(SUM(amount) FILTER (WHERE status <> $1 AND timestamp >= $4), 0),
+        COALESCE(MAX(timestamp), 0) FROM refills WHERE chain_id = $5`
+	row := db.conn.QueryRowContext(ctx, query, RefillFailed, RefillUnconfirmed, RefillSucceeded, time.Now().Add(-24*time.Hour).Unix(), chainID)
+	if err := row.Scan(&stats.Attempts, &stats.Failed, &stats.Unconfirmed, &stats.TotalAmount, &stats.Last24hAmount, &stats.LastTimestamp); err != nil {
+		log.Printf("Error fetching refill stats: %v", err)
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// AddUsedSolution records a paid out solution. The primary key makes the
+// insert fail for a pair that is already recorded, even by another instance.
+func (db *DB) AddUsedSolution(ctx context.Context, saltID, solutionHash string, expiresAt int64) (bool, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.AddUsedSolution")
+	defer span.End()
+
+	query := `INSERT INTO used_solutions (salt_id, solution_hash, expires_at) VALUES ($1, $2, $3)
+        ON CONFLICT (salt_id, solution_hash) DO NOTHING`
+	result, err := db.conn.ExecContext(ctx, query, saltID, solutionHash, expiresAt)
+	if err != nil {
+		log.Printf("Error saving used solution: %v", err)
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error saving used solution: %v", err)
+		return false, err
+	}
+	return added == 1, nil
+}
+
+func (db *DB) RemoveUsedSolution(ctx context.Context, saltID, solutionHash string) error {
+	ctx, span := db.tracer.Start(ctx, "DB.RemoveUsedSolution")
+	defer span.End()
+
+	query := `DELETE FROM used_solutions WHERE salt_id = $1 AND solution_hash = $2`
+	_, err := db.conn.ExecContext(ctx, query, saltID, solutionHash)
+	if err != nil {
+		log.Printf("Error removing used solution: %v", err)
+	}
+	return err
+}
+
+func (db *DB) PruneUsedSolutions(ctx context.Context, now int64) (int64, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.PruneUsedSolutions")
+	defer span.End()
+
+	query := `DELETE FROM used_solutions WHERE expires_at < $1`
+	result, err := db.conn.ExecContext(ctx, query, now)
+	if err != nil {
+		log.Printf("Error pruning used solutions: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const voucherColumns = `code, batch_id, chain_id, asset, amount, max_uses, uses, expires_at, revoked_at, created_by, created_at`
+
+func scanVoucher(row scanner) (Voucher, error) {
+	var v Voucher
+	err := row.Scan(&v.Code, &v.BatchID, &v.ChainID, &v.Asset, &v.Amount, &v.MaxUses, &v.Uses, &v.ExpiresAt, &v.RevokedAt, &v.CreatedBy, &v.CreatedAt)
+	return v, err
+}
+
+// SaveVouchers saves vouchers in a single transaction, so either all or none
+// of a batch is saved
+func (db *DB) SaveVouchers(ctx context.Context, vouchers []Voucher) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SaveVouchers")
+	defer span.End()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Error saving vouchers: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	createdAt := time.Now().Unix()
+	query := `INSERT INTO vouchers (` + voucherColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	for i := range vouchers {
+		v := &vouchers[i]
+		v.CreatedAt = createdAt
+		if _, err := tx.ExecContext(ctx, query, v.Code, v.BatchID, v.ChainID, v.Asset, v.Amount, v.MaxUses, v.Uses, v.ExpiresAt, v.RevokedAt, v.CreatedBy, v.CreatedAt); err != nil {
+			log.Printf("Error saving voucher: %v", err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error saving vouchers: %v", err)
+		return err
+	}
+	if len(vouchers) > 0 {
+		log.Printf("Saved vouchers: batchID=%s, count=%d, createdBy=%s", vouchers[0].BatchID, len(vouchers), vouchers[0].CreatedBy)
+	}
+	return nil
+}
+
+func (db *DB) GetVouchers(ctx context.Context, batchID string) ([]Voucher, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetVouchers")
+	defer span.End()
+
+	query := `SELECT ` + voucherColumns + ` FROM vouchers WHERE batch_id = $1 ORDER BY code`
+	rows, err := db.conn.QueryContext(ctx, query, batchID)
+	if err != nil {
+		log.Printf("Error fetching vouchers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vouchers []Voucher
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			log.Printf("Error scanning voucher row: %v", err)
+			return nil, err
+		}
+		vouchers = append(vouchers, v)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+	return vouchers, nil
+}
+
+// RevokeVouchers leaves the vouchers that are already revoked untouched
+func (db *DB) RevokeVouchers(ctx context.Context, batchID, code string) (int64, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.RevokeVouchers")
+	defer span.End()
+
+	log.Printf("Revoking vouchers: batchID=%s, code=%s", batchID, code)
+	query := `UPDATE vouchers SET revoked_at = $1 WHERE revoked_at = 0 AND `
+	key := code
+	if batchID != "" {
+		query, key = query+`batch_id = $2`, batchID
+	} else {
+		query += `code = $2`
+	}
+	result, err := db.conn.ExecContext(ctx, query, time.Now().Unix(), key)
+	if err != nil {
+		log.Printf("Error revoking vouchers: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RedeemVoucher takes the use with a conditional update, so concurrent
+// redemptions, even by other instances, cannot exceed the maximum uses
+func (db *DB) RedeemVoucher(ctx context.Context, chainID, code, address string, now int64) (*Voucher, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.RedeemVoucher")
+	defer span.End()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Error redeeming voucher: %v", err)
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE vouchers SET uses = uses + 1
+        WHERE code = $1 AND chain_id = $2 AND revoked_at = 0 AND (expires_at = 0 OR expires_at > $3) AND uses < max_uses`
+	result, err := tx.ExecContext(ctx, query, code, chainID, now)
+	if err != nil {
+		log.Printf("Error redeeming voucher: %v", err)
+		return nil, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error redeeming voucher: %v", err)
+		return nil, err
+	}
+
+	query = `INSERT INTO voucher_redemptions (code, address, timestamp) VALUES ($1, $2, $3)
+        ON CONFLICT (code, address) DO NOTHING`
+	result, err = tx.ExecContext(ctx, query, code, address, now)
+	if err != nil {
+		log.Printf("Error saving voucher redemption: %v", err)
+		return nil, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error saving voucher redemption: %v", err)
+		return nil, err
+	}
+
+	v, err := scanVoucher(tx.QueryRowContext(ctx, `SELECT `+voucherColumns+` FROM vouchers WHERE code = $1`, code))
+	if err == sql.ErrNoRows || (err == nil && v.ChainID != chainID) {
+		return nil, ErrVoucherNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching voucher: %v", err)
+		return nil, err
+	}
+	if updated == 0 || inserted == 0 {
+		// The rollback gives back the use taken above
+		if updated == 1 {
+			v.Uses--
+		}
+		return nil, v.redeemError(now, inserted == 0)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error redeeming voucher: %v", err)
+		return nil, err
+	}
+	log.Printf("Redeemed voucher: code=%s, address=%s, uses=%d/%d", code, address, v.Uses, v.MaxUses)
+	return &v, nil
+}
+
+func (db *DB) CompleteRedemption(ctx context.Context, code, address, txID string) error {
+	ctx, span := db.tracer.Start(ctx, "DB.CompleteRedemption")
+	defer span.End()
+
+	query := `UPDATE voucher_redemptions SET txid = $1 WHERE code = $2 AND address = $3`
+	_, err := db.conn.ExecContext(ctx, query, txID, code, address)
+	if err != nil {
+		log.Printf("Error completing voucher redemption: %v", err)
+	}
+	return err
+}
+
+// ReleaseRedemption only releases redemptions that were not completed
+func (db *DB) ReleaseRedemption(ctx context.Context, code, address string) error {
+	ctx, span := db.tracer.Start(ctx, "DB.ReleaseRedemption")
+	defer span.End()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Error releasing voucher redemption: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `DELETE FROM voucher_redemptions WHERE code = $1 AND address = $2 AND txid = ''`
+	result, err := tx.ExecContext(ctx, query, code, address)
+	if err != nil {
+		log.Printf("Error releasing voucher redemption: %v", err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return err
+	}
+	query = `UPDATE vouchers SET uses = uses - 1 WHERE code = $1 AND uses > 0`
+	if _, err := tx.ExecContext(ctx, query, code); err != nil {
+		log.Printf("Error releasing voucher redemption: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+const apiKeyColumns = `id, name, key_hash, daily_amount, daily_requests, allowed_assets, allowed_destinations,
+        created_by, created_at, revoked_at`
+
+func scanAPIKey(row scanner) (APIKey, error) {
+	var (
+		key          APIKey
+		assets       string
+		destinations string
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &key.DailyAmount, &key.DailyRequests, &assets, &destinations,
+		&key.CreatedBy, &key.CreatedAt, &key.RevokedAt)
+	key.AllowedAssets, key.AllowedDestinations = splitList(assets), splitList(destinations)
+	return key, err
+}
+
+func (db *DB) SaveAPIKey(ctx context.Context, key *APIKey) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SaveAPIKey")
+	defer span.End()
+
+	key.CreatedAt = time.Now().Unix()
+	log.Printf("Saving API key: id=%s, name=%q, createdBy=%s", key.ID, key.Name, key.CreatedBy)
+	query := `INSERT INTO api_keys (` + apiKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := db.conn.ExecContext(ctx, query, key.ID, key.Name, key.KeyHash, key.DailyAmount, key.DailyRequests,
+		joinList(key.AllowedAssets), joinList(key.AllowedDestinations), key.CreatedBy, key.CreatedAt, key.RevokedAt)
+	if err != nil {
+		log.Printf("Error saving API key: %v", err)
+	}
+	return err
+}
+
+func (db *DB) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAPIKeyByHash")
+	defer span.End()
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	key, err := scanAPIKey(db.conn.QueryRowContext(ctx, query, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching API key: %v", err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (db *DB) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAPIKeys")
+	defer span.End()
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at, id`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		log.Printf("Error fetching API keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			log.Printf("Error scanning API key row: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (db *DB) RevokeAPIKey(ctx context.Context, id string) (bool, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.RevokeAPIKey")
+	defer span.End()
+
+	log.Printf("Revoking API key: id=%s", id)
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at = 0`
+	result, err := db.conn.ExecContext(ctx, query, time.Now().Unix(), id)
+	if err != nil {
+		log.Printf("Error revoking API key: %v", err)
+		return false, err
+	}
+	revoked, err := result.RowsAffected()
+	return revoked == 1, err
+}
+
+// UseAPIKey checks the quotas in the update itself, so concurrent requests,
+// even to other instances, cannot exceed them
+func (db *DB) UseAPIKey(ctx context.Context, key *APIKey, day int64, amount uint64) (bool, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.UseAPIKey")
+	defer span.End()
+
+	// The first request of a day is inserted without going through the
+	// conditions of the update
+	if !key.allows(&APIKeyUsage{}, amount) {
+		return false, nil
+	}
+	query := `INSERT INTO api_key_usage (key_id, day, requests, amount) VALUES ($1, $2, 1, $3)
+        ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1, amount = api_key_usage.amount + EXCLUDED.amount
+        WHERE ($4 = 0 OR api_key_usage.requests < $4) AND ($5 = 0 OR api_key_usage.amount + EXCLUDED.amount <= $5)`
+	result, err := db.conn.ExecContext(ctx, query, key.ID, day, amount, key.DailyRequests, key.DailyAmount)
+	if err != nil {
+		log.Printf("Error using API key: %v", err)
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error using API key: %v", err)
+		return false, err
+	}
+	return used == 1, nil
+}
+
+func (db *DB) ReleaseAPIKeyUse(ctx context.Context, id string, day int64, amount uint64) error {
+	ctx, span := db.tracer.Start(ctx, "DB.ReleaseAPIKeyUse")
+	defer span.End()
+
+	query := `UPDATE api_key_usage SET requests = requests - 1, amount = amount - $1
+        WHERE key_id = $2 AND day = $3 AND requests > 0 AND amount >= $1`
+	_, err := db.conn.ExecContext(ctx, query, amount, id, day)
+	if err != nil {
+		log.Printf("Error releasing API key use: %v", err)
+	}
+	return err
+}
+
+func (db *DB) GetAPIKeyUsage(ctx context.Context, id string, since int64) ([]APIKeyUsage, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAPIKeyUsage")
+	defer span.End()
+
+	query := `SELECT key_id, day, requests, amount FROM api_key_usage
+        WHERE ($1 = '' OR key_id = $1) AND day >= $2 ORDER BY day DESC, key_id`
+	rows, err := db.conn.QueryContext(ctx, query, id, since)
+	if err != nil {
+		log.Printf("Error fetching API key usage: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []APIKeyUsage
+	for rows.Next() {
+		var u APIKeyUsage
+		if err := rows.Scan(&u.KeyID, &u.Day, &u.Requests, &u.Amount); err != nil {
+			log.Printf("Error scanning API key usage row: %v", err)
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+	return usage, nil
+}
+
+const airdropRowColumns = `airdrop_id, line, address, amount, status, txid, error, updated_at`
+
+func (db *DB) SaveAirdrop(ctx context.Context, airdrop *Airdrop, rows []AirdropRow) error {
+	ctx, span := db.tracer.Start(ctx, "DB.SaveAirdrop")
+	defer span.End()
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("Error saving airdrop: %v", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	airdrop.CreatedAt = time.Now().Unix()
+	query := `INSERT INTO airdrops (id, chain_id, asset, name, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(ctx, query, airdrop.ID, airdrop.ChainID, airdrop.Asset, airdrop.Name, airdrop.CreatedBy, airdrop.CreatedAt); err != nil {
+		log.Printf("Error saving airdrop: %v", err)
+		return err
+	}
+	query = `INSERT INTO airdrop_rows (` + airdropRowColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	for i := range rows {
+		r := &rows[i]
+		if _, err := tx.ExecContext(ctx, query, r.AirdropID, r.Line, r.Address, r.Amount, r.Status, r.TxID, r.Error, r.UpdatedAt); err != nil {
+			log.Printf("Error saving airdrop row: %v", err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error saving airdrop: %v", err)
+		return err
+	}
+	log.Printf("Saved airdrop: id=%s, rows=%d, createdBy=%s", airdrop.ID, len(rows), airdrop.CreatedBy)
+	return nil
+}
+
+func (db *DB) GetAirdrop(ctx context.Context, id string) (*Airdrop, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAirdrop")
+	defer span.End()
+
+	query := `SELECT id, chain_id, asset, name, created_by, created_at FROM airdrops WHERE id = $1`
+	var a Airdrop
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(&a.ID, &a.ChainID, &a.Asset, &a.Name, &a.CreatedBy, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAirdropNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching airdrop: %v", err)
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *DB) GetAirdropRows(ctx context.Context, id, status string, limit int) ([]AirdropRow, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.GetAirdropRows")
+	defer span.End()
+
+	query := `SELECT ` + airdropRowColumns + ` FROM airdrop_rows
+        WHERE airdrop_id = $1 AND ($2 = '' OR status = $2) ORDER BY line`
+	args := []any{id, status}
+	if limit > 0 {
+		query += ` LIMIT $3`
+		args = append(args, limit)
+	}
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("Error fetching airdrop rows: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var airdropRows []AirdropRow
+	for rows.Next() {
+		var r AirdropRow
+		if err := rows.Scan(&r.AirdropID, &r.Line, &r.Address, &r.Amount, &r.Status, &r.TxID, &r.Error, &r.UpdatedAt); err != nil {
+			log.Printf("Error scanning airdrop row: %v", err)
+			return nil, err
+		}
+		airdropRows = append(airdropRows, r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error in rows: %v", err)
+		return nil, err
+	}
+	return airdropRows, nil
+}
+
+func (db *DB) UpdateAirdropRow(ctx context.Context, row *AirdropRow, from string) (bool, error) {
+	ctx, span := db.tracer.Start(ctx, "DB.UpdateAirdropRow")
+	defer span.End()
+
+	query := `UPDATE airdrop_rows SET status = $1, txid = $2, error = $3, updated_at = $4
+        WHERE airdrop_id = $5 AND line = $6 AND status = $7`
+	result, err := db.conn.ExecContext(ctx, query, row.Status, row.TxID, row.Error, row.UpdatedAt, row.AirdropID, row.Line, from)
+	if err != nil {
+		log.Printf("Error updating airdrop row: %v", err)
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error updating airdrop row: %v", err)
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+// Ping checks that the database is reachable
+func (db *DB) Ping(ctx context.Context) error {
+	ctx, span := db.tracer.Start(ctx, "DB.Ping")
+	defer span.End()
+
+	return db.conn.PingContext(ctx)
+}
+
 func (db *DB) Close() {
 	log.Println("Closing database connection")
 	db.conn.Close()
@@ -0,0 +1,544 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ Store = (*Memory)(nil)
+
+// Memory is a Store that keeps everything in memory. It is meant for local
+// development and tests, everything is lost on restart.
+type Memory struct {
+	l            sync.RWMutex
+	transactions []Transaction // in insertion order
+	denied       map[string]DeniedAddress
+	pause        map[string]PauseState // by chain ID
+	refills      []Refill
+	solutions    map[usedSolution]int64 // expiry of each used solution
+	vouchers     map[string]Voucher     // by code
+	redemptions  map[redemption]string  // txID of each redemption, empty until paid out
+	apiKeys      []APIKey               // in creation order
+	apiKeyUsage  map[apiKeyDay]APIKeyUsage
+	airdrops     map[string]Airdrop
+	airdropRows  map[string][]AirdropRow // by airdrop ID, ordered by line
+}
+
+type apiKeyDay struct {
+	id  string
+	day int64
+}
+
+type redemption struct {
+	code    string
+	address string
+}
+
+type usedSolution struct {
+	saltID       string
+	solutionHash string
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		denied:      map[string]DeniedAddress{},
+		pause:       map[string]PauseState{},
+		solutions:   map[usedSolution]int64{},
+		vouchers:    map[string]Voucher{},
+		redemptions: map[redemption]string{},
+		apiKeyUsage: map[apiKeyDay]APIKeyUsage{},
+		airdrops:    map[string]Airdrop{},
+		airdropRows: map[string][]AirdropRow{},
+	}
+}
+
+func (m *Memory) SaveTransaction(_ context.Context, txn *Transaction) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	txn.Timestamp = time.Now().Unix()
+	m.transactions = append(m.transactions, *txn)
+	return nil
+}
+
+func (m *Memory) GetTransaction(_ context.Context, txID string) (*Transaction, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	for _, txn := range m.transactions {
+		if txn.TxID == txID {
+			return &txn, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *Memory) GetAllTransactions(_ context.Context) ([]Transaction, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return append([]Transaction(nil), m.transactions...), nil
+}
+
+func (m *Memory) SearchTransactions(_ context.Context, filter *TransactionFilter) ([]Transaction, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var transactions []Transaction
+	for _, txn := range m.transactions {
+		if filter.matches(&txn) {
+			transactions = append(transactions, txn)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Timestamp > transactions[j].Timestamp
+	})
+	if filter.Limit > 0 && len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+	}
+	return transactions, nil
+}
+
+func (m *Memory) GetStats(_ context.Context, chainID string) (*Stats, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var (
+		stats        Stats
+		destinations = map[string]struct{}{}
+		since        = time.Now().Add(-24 * time.Hour).Unix()
+	)
+	for _, txn := range m.transactions {
+		if (chainID != "" && txn.ChainID != chainID) || txn.Asset != "" {
+			continue
+		}
+		stats.TotalTransactions++
+		stats.TotalAmount += txn.Amount
+		destinations[txn.Destination] = struct{}{}
+		stats.LastTimestamp = max(stats.LastTimestamp, txn.Timestamp)
+		if txn.Timestamp >= since {
+			stats.Last24hTransactions++
+			stats.Last24hAmount += txn.Amount
+		}
+	}
+	stats.UniqueDestinations = uint64(len(destinations))
+	return &stats, nil
+}
+
+func (m *Memory) AddDeniedAddress(_ context.Context, address, reason string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.denied[address] = DeniedAddress{Address: address, Reason: reason, Timestamp: time.Now().Unix()}
+	return nil
+}
+
+func (m *Memory) RemoveDeniedAddress(_ context.Context, address string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	delete(m.denied, address)
+	return nil
+}
+
+func (m *Memory) GetDeniedAddresses(_ context.Context) ([]DeniedAddress, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var denied []DeniedAddress
+	for _, d := range m.denied {
+		denied = append(denied, d)
+	}
+	sort.Slice(denied, func(i, j int) bool {
+		if denied[i].Timestamp != denied[j].Timestamp {
+			return denied[i].Timestamp > denied[j].Timestamp
+		}
+		return denied[i].Address < denied[j].Address
+	})
+	return denied, nil
+}
+
+func (m *Memory) SavePauseState(_ context.Context, chainID string, state *PauseState) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	state.UpdatedAt = time.Now().Unix()
+	m.pause[chainID] = *state
+	return nil
+}
+
+func (m *Memory) GetPauseState(_ context.Context, chainID string) (*PauseState, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	state := m.pause[chainID]
+	return &state, nil
+}
+
+func (m *Memory) SaveRefill(_ context.Context, refill *Refill) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	refill.Timestamp = time.Now().Unix()
+	m.refills = append(m.refills, *refill)
+	return nil
+}
+
+func (m *Memory) GetRefilledSince(_ context.Context, chainID string, since int64) (uint64, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var amount uint64
+	for _, refill := range m.refills {
+		if refill.ChainID == chainID && refill.Status != RefillFailed && refill.Timestamp >= since {
+			amount += refill.Amount
+		}
+	}
+	return amount, nil
+}
+
+func (m *Memory) GetAdminPaidSince(_ context.Context, chainID string, since int64) (uint64, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var amount uint64
+	for _, txn := range m.transactions {
+		if txn.ChainID == chainID && txn.Admin != "" && txn.Asset == "" && txn.Timestamp >= since {
+			amount += txn.Amount
+		}
+	}
+	return amount, nil
+}
+
+func (m *Memory) GetRefillStats(_ context.Context, chainID string) (*RefillStats, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var (
+		stats RefillStats
+		since = time.Now().Add(-24 * time.Hour).Unix()
+	)
+	for _, refill := range m.refills {
+		if refill.ChainID != chainID {
+			continue
+		}
+		stats.Attempts++
+		stats.LastTimestamp = max(stats.LastTimestamp, refill.Timestamp)
+		switch refill.Status {
+		case RefillFailed:
+			stats.Failed++
+			continue
+		case RefillUnconfirmed:
+			stats.Unconfirmed++
+		case RefillSucceeded:
+			stats.TotalAmount += refill.Amount
+		}
+		if refill.Timestamp >= since {
+			stats.Last24hAmount += refill.Amount
+		}
+	}
+	return &stats, nil
+}
+
+func (m *Memory) AddUsedSolution(_ context.Context, saltID, solutionHash string, expiresAt int64) (bool, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	key := usedSolution{saltID: saltID, solutionHash: solutionHash}
+	if _, ok := m.solutions[key]; ok {
+		return false, nil
+	}
+	m.solutions[key] = expiresAt
+	return true, nil
+}
+
+func (m *Memory) RemoveUsedSolution(_ context.Context, saltID, solutionHash string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	delete(m.solutions, usedSolution{saltID: saltID, solutionHash: solutionHash})
+	return nil
+}
+
+func (m *Memory) PruneUsedSolutions(_ context.Context, now int64) (int64, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	var pruned int64
+	for key, expiresAt := range m.solutions {
+		if expiresAt < now {
+			delete(m.solutions, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (m *Memory) SaveVouchers(_ context.Context, vouchers []Voucher) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, v := range vouchers {
+		if _, ok := m.vouchers[v.Code]; ok {
+			return fmt.Errorf("voucher %s already exists", v.Code)
+		}
+	}
+	createdAt := time.Now().Unix()
+	for i := range vouchers {
+		vouchers[i].CreatedAt = createdAt
+		m.vouchers[vouchers[i].Code] = vouchers[i]
+	}
+	return nil
+}
+
+func (m *Memory) GetVouchers(_ context.Context, batchID string) ([]Voucher, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var vouchers []Voucher
+	for _, v := range m.vouchers {
+		if v.BatchID == batchID {
+			vouchers = append(vouchers, v)
+		}
+	}
+	sort.Slice(vouchers, func(i, j int) bool {
+		return vouchers[i].Code < vouchers[j].Code
+	})
+	return vouchers, nil
+}
+
+func (m *Memory) RevokeVouchers(_ context.Context, batchID, code string) (int64, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	var revoked int64
+	now := time.Now().Unix()
+	for key, v := range m.vouchers {
+		if v.RevokedAt != 0 || (batchID != "" && v.BatchID != batchID) || (batchID == "" && v.Code != code) {
+			continue
+		}
+		v.RevokedAt = now
+		m.vouchers[key] = v
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (m *Memory) RedeemVoucher(_ context.Context, chainID, code, address string, now int64) (*Voucher, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	v, ok := m.vouchers[code]
+	if !ok || v.ChainID != chainID {
+		return nil, ErrVoucherNotFound
+	}
+	key := redemption{code: code, address: address}
+	_, redeemed := m.redemptions[key]
+	if err := v.redeemError(now, redeemed); err != nil {
+		return nil, err
+	}
+	v.Uses++
+	m.vouchers[code] = v
+	m.redemptions[key] = ""
+	return &v, nil
+}
+
+func (m *Memory) CompleteRedemption(_ context.Context, code, address, txID string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	key := redemption{code: code, address: address}
+	if _, ok := m.redemptions[key]; ok {
+		m.redemptions[key] = txID
+	}
+	return nil
+}
+
+func (m *Memory) ReleaseRedemption(_ context.Context, code, address string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	key := redemption{code: code, address: address}
+	if txID, ok := m.redemptions[key]; !ok || txID != "" {
+		return nil
+	}
+	delete(m.redemptions, key)
+	if v, ok := m.vouchers[code]; ok && v.Uses > 0 {
+		v.Uses--
+		m.vouchers[code] = v
+	}
+	return nil
+}
+
+func (m *Memory) SaveAPIKey(_ context.Context, key *APIKey) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.ID == key.ID || k.KeyHash == key.KeyHash {
+			return fmt.Errorf("API key %s already exists", key.ID)
+		}
+	}
+	key.CreatedAt = time.Now().Unix()
+	m.apiKeys = append(m.apiKeys, *key)
+	return nil
+}
+
+func (m *Memory) GetAPIKeyByHash(_ context.Context, keyHash string) (*APIKey, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	for _, k := range m.apiKeys {
+		if k.KeyHash == keyHash {
+			return &k, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (m *Memory) GetAPIKeys(_ context.Context) ([]APIKey, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return append([]APIKey(nil), m.apiKeys...), nil
+}
+
+func (m *Memory) RevokeAPIKey(_ context.Context, id string) (bool, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for i := range m.apiKeys {
+		if m.apiKeys[i].ID == id && m.apiKeys[i].RevokedAt == 0 {
+			m.apiKeys[i].RevokedAt = time.Now().Unix()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) UseAPIKey(_ context.Context, key *APIKey, day int64, amount uint64) (bool, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	k := apiKeyDay{id: key.ID, day: day}
+	usage, ok := m.apiKeyUsage[k]
+	if !ok {
+		usage = APIKeyUsage{KeyID: key.ID, Day: day}
+	}
+	if !key.allows(&usage, amount) {
+		return false, nil
+	}
+	usage.Requests++
+	usage.Amount += amount
+	m.apiKeyUsage[k] = usage
+	return true, nil
+}
+
+func (m *Memory) ReleaseAPIKeyUse(_ context.Context, id string, day int64, amount uint64) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	k := apiKeyDay{id: id, day: day}
+	usage, ok := m.apiKeyUsage[k]
+	if ok && usage.Requests > 0 && usage.Amount >= amount {
+		usage.Requests--
+		usage.Amount -= amount
+		m.apiKeyUsage[k] = usage
+	}
+	return nil
+}
+
+func (m *Memory) GetAPIKeyUsage(_ context.Context, id string, since int64) ([]APIKeyUsage, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var usage []APIKeyUsage
+	for k, u := range m.apiKeyUsage {
+		if (id == "" || k.id == id) && k.day >= since {
+			usage = append(usage, u)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Day != usage[j].Day {
+			return usage[i].Day > usage[j].Day
+		}
+		return usage[i].KeyID < usage[j].KeyID
+	})
+	return usage, nil
+}
+
+func (m *Memory) SaveAirdrop(_ context.Context, airdrop *Airdrop, rows []AirdropRow) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if _, ok := m.airdrops[airdrop.ID]; ok {
+		return fmt.Errorf("airdrop %s already exists", airdrop.ID)
+	}
+	airdrop.CreatedAt = time.Now().Unix()
+	m.airdrops[airdrop.ID] = *airdrop
+	saved := append([]AirdropRow(nil), rows...)
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Line < saved[j].Line })
+	m.airdropRows[airdrop.ID] = saved
+	return nil
+}
+
+func (m *Memory) GetAirdrop(_ context.Context, id string) (*Airdrop, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	a, ok := m.airdrops[id]
+	if !ok {
+		return nil, ErrAirdropNotFound
+	}
+	return &a, nil
+}
+
+func (m *Memory) GetAirdropRows(_ context.Context, id, status string, limit int) ([]AirdropRow, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	var rows []AirdropRow
+	for _, r := range m.airdropRows[id] {
+		if limit > 0 && len(rows) == limit {
+			break
+		}
+		if status == "" || r.Status == status {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+func (m *Memory) UpdateAirdropRow(_ context.Context, row *AirdropRow, from string) (bool, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	rows := m.airdropRows[row.AirdropID]
+	for i := range rows {
+		if rows[i].Line != row.Line {
+			continue
+		}
+		if rows[i].Status != from {
+			return false, nil
+		}
+		rows[i].Status = row.Status
+		rows[i].TxID = row.TxID
+		rows[i].Error = row.Error
+		rows[i].UpdatedAt = row.UpdatedAt
+		return true, nil
+	}
+	return false, nil
+}
+
+func (*Memory) Ping(context.Context) error {
+	return nil
+}
+
+func (*Memory) Close() {}
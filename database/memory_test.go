@@ -0,0 +1,17 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database_test
+
+import (
+	"testing"
+
+	"github.com/nuklai/nuklai-faucet/database"
+	"github.com/nuklai/nuklai-faucet/database/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(*testing.T) database.Store {
+		return database.NewMemory()
+	})
+}
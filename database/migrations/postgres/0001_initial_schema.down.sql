@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS refills;
+DROP TABLE IF EXISTS deny_list;
+DROP TABLE IF EXISTS faucet_state;
+DROP TABLE IF EXISTS transactions;
@@ -0,0 +1,31 @@
+-- Tables created by releases before versioned migrations already exist on
+-- deployed databases, so they are only created when missing
+CREATE TABLE IF NOT EXISTS transactions (
+    txid TEXT PRIMARY KEY,
+    destination TEXT,
+    amount BIGINT,
+    timestamp BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS faucet_state (
+    id INTEGER PRIMARY KEY,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    resume_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS deny_list (
+    address TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    timestamp BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS refills (
+    id SERIAL PRIMARY KEY,
+    txid TEXT NOT NULL DEFAULT '',
+    amount BIGINT,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    timestamp BIGINT
+);
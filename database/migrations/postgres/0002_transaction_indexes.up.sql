@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS transactions_destination_idx ON transactions (destination);
+CREATE INDEX IF NOT EXISTS transactions_timestamp_idx ON transactions (timestamp);
+CREATE INDEX IF NOT EXISTS refills_timestamp_idx ON refills (timestamp);
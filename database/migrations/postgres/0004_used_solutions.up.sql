@@ -0,0 +1,8 @@
+CREATE TABLE used_solutions (
+    salt_id TEXT NOT NULL,
+    solution_hash TEXT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    PRIMARY KEY (salt_id, solution_hash)
+);
+
+CREATE INDEX used_solutions_expires_at_idx ON used_solutions (expires_at);
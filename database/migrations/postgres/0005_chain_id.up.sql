@@ -0,0 +1,19 @@
+-- Rows recorded before the faucet served several chains keep an empty chain
+-- ID
+ALTER TABLE transactions ADD COLUMN chain_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX transactions_chain_id_idx ON transactions (chain_id);
+
+ALTER TABLE refills ADD COLUMN chain_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX refills_chain_id_idx ON refills (chain_id);
+
+-- The single row of faucet_state becomes a row per chain
+CREATE TABLE pause_states (
+    chain_id TEXT PRIMARY KEY,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    resume_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+);
+INSERT INTO pause_states (chain_id, paused, message, resume_at, updated_at)
+    SELECT '', paused, message, resume_at, updated_at FROM faucet_state WHERE id = 1;
+DROP TABLE faucet_state;
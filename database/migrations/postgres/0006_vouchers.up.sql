@@ -0,0 +1,25 @@
+ALTER TABLE transactions ADD COLUMN asset TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE vouchers (
+    code TEXT PRIMARY KEY,
+    batch_id TEXT NOT NULL,
+    chain_id TEXT NOT NULL,
+    asset TEXT NOT NULL DEFAULT '',
+    amount BIGINT NOT NULL,
+    max_uses INTEGER NOT NULL,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    revoked_at BIGINT NOT NULL DEFAULT 0,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX vouchers_batch_id_idx ON vouchers (batch_id);
+
+CREATE TABLE voucher_redemptions (
+    code TEXT NOT NULL,
+    address TEXT NOT NULL,
+    txid TEXT NOT NULL DEFAULT '',
+    timestamp BIGINT NOT NULL,
+    PRIMARY KEY (code, address)
+);
@@ -0,0 +1,5 @@
+DROP TABLE api_key_usage;
+DROP TABLE api_keys;
+
+DROP INDEX transactions_api_key_id_idx;
+ALTER TABLE transactions DROP COLUMN api_key_id;
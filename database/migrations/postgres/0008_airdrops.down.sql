@@ -0,0 +1,2 @@
+DROP TABLE airdrop_rows;
+DROP TABLE airdrops;
@@ -0,0 +1,23 @@
+-- Lists of payouts sent by the admins in batches
+CREATE TABLE airdrops (
+    id TEXT PRIMARY KEY,
+    chain_id TEXT NOT NULL,
+    asset TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+-- One payout per line of the list, numbered from 1. Lines go from pending
+-- to sending, then to sent or failed.
+CREATE TABLE airdrop_rows (
+    airdrop_id TEXT NOT NULL,
+    line INTEGER NOT NULL,
+    address TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    txid TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (airdrop_id, line)
+);
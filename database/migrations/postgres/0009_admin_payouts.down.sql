@@ -0,0 +1,3 @@
+DROP INDEX transactions_admin_idx;
+ALTER TABLE transactions DROP COLUMN reason;
+ALTER TABLE transactions DROP COLUMN admin;
@@ -0,0 +1,4 @@
+-- Payouts sent by an admin record its key name and why
+ALTER TABLE transactions ADD COLUMN admin TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+CREATE INDEX transactions_admin_idx ON transactions (admin);
@@ -0,0 +1,29 @@
+CREATE TABLE transactions (
+    txid TEXT PRIMARY KEY,
+    destination TEXT,
+    amount BIGINT,
+    timestamp BIGINT
+);
+
+CREATE TABLE faucet_state (
+    id INTEGER PRIMARY KEY,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    resume_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE deny_list (
+    address TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    timestamp BIGINT
+);
+
+CREATE TABLE refills (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    txid TEXT NOT NULL DEFAULT '',
+    amount BIGINT,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    timestamp BIGINT
+);
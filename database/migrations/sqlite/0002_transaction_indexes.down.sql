@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS refills_timestamp_idx;
+DROP INDEX IF EXISTS transactions_timestamp_idx;
+DROP INDEX IF EXISTS transactions_destination_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX transactions_destination_idx ON transactions (destination);
+CREATE INDEX transactions_timestamp_idx ON transactions (timestamp);
+CREATE INDEX refills_timestamp_idx ON refills (timestamp);
@@ -0,0 +1,12 @@
+DROP INDEX transactions_solution_hash_idx;
+DROP INDEX transactions_salt_id_idx;
+DROP INDEX transactions_client_ip_idx;
+
+ALTER TABLE transactions DROP COLUMN processing_time;
+ALTER TABLE transactions DROP COLUMN rpc_endpoint;
+ALTER TABLE transactions DROP COLUMN user_agent;
+ALTER TABLE transactions DROP COLUMN client_ip;
+ALTER TABLE transactions DROP COLUMN solution_hash;
+ALTER TABLE transactions DROP COLUMN salt_id;
+ALTER TABLE transactions DROP COLUMN difficulty;
+ALTER TABLE transactions DROP COLUMN fee;
@@ -0,0 +1,12 @@
+ALTER TABLE transactions ADD COLUMN fee BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN difficulty INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN salt_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN solution_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN client_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN rpc_endpoint TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN processing_time BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX transactions_client_ip_idx ON transactions (client_ip);
+CREATE INDEX transactions_salt_id_idx ON transactions (salt_id);
+CREATE INDEX transactions_solution_hash_idx ON transactions (solution_hash);
@@ -0,0 +1,16 @@
+CREATE TABLE faucet_state (
+    id INTEGER PRIMARY KEY,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    resume_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL DEFAULT 0
+);
+INSERT INTO faucet_state (id, paused, message, resume_at, updated_at)
+    SELECT 1, paused, message, resume_at, updated_at FROM pause_states WHERE chain_id = '';
+DROP TABLE pause_states;
+
+DROP INDEX refills_chain_id_idx;
+ALTER TABLE refills DROP COLUMN chain_id;
+
+DROP INDEX transactions_chain_id_idx;
+ALTER TABLE transactions DROP COLUMN chain_id;
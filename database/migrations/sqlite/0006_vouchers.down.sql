@@ -0,0 +1,4 @@
+DROP TABLE voucher_redemptions;
+DROP TABLE vouchers;
+
+ALTER TABLE transactions DROP COLUMN asset;
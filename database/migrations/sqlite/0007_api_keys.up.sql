@@ -0,0 +1,26 @@
+ALTER TABLE transactions ADD COLUMN api_key_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX transactions_api_key_id_idx ON transactions (api_key_id);
+
+-- Only a hash of each key is stored. Allowed assets and destinations are
+-- comma separated, empty for the defaults.
+CREATE TABLE api_keys (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    daily_amount BIGINT NOT NULL DEFAULT 0,
+    daily_requests INTEGER NOT NULL DEFAULT 0,
+    allowed_assets TEXT NOT NULL DEFAULT '',
+    allowed_destinations TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    revoked_at BIGINT NOT NULL DEFAULT 0
+);
+
+-- Usage of each key per UTC day, counted in days since the unix epoch
+CREATE TABLE api_key_usage (
+    key_id TEXT NOT NULL,
+    day BIGINT NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
@@ -0,0 +1,3 @@
+-- Payouts whose transaction was sent but not confirmed may have been
+-- accepted, they are recorded so they count toward the admin budget
+ALTER TABLE transactions ADD COLUMN unconfirmed BOOLEAN NOT NULL DEFAULT FALSE;
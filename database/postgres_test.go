@@ -0,0 +1,77 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database_test
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/trace"
+	"github.com/nuklai/nuklai-faucet/database"
+	"github.com/nuklai/nuklai-faucet/database/storetest"
+)
+
+// postgresDSNEnv names the variable holding the connection string of a
+// PostgreSQL database the tests can create schemas in, e.g.
+// "host=localhost user=postgres password=postgres dbname=faucet sslmode=disable"
+const postgresDSNEnv = "FAUCET_TEST_POSTGRES_DSN"
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	admin, err := sql.Open(string(database.Postgres), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	// Every test gets its own schema, dropped once the store is closed
+	storetest.Run(t, func(t *testing.T) database.Store {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		schema := "storetest_" + hex.EncodeToString(b)
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+				t.Errorf("dropping schema %s: %v", schema, err)
+			}
+		})
+		conn, err := sql.Open(string(database.Postgres), withSearchPath(dsn, schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, err := database.NewDB(conn, database.Postgres, trace.Noop)
+		if err != nil {
+			conn.Close()
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// withSearchPath adds the search_path parameter to dsn, given as a URL or as
+// key=value pairs
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/trace"
+	"github.com/nuklai/nuklai-faucet/database"
+	"github.com/nuklai/nuklai-faucet/database/storetest"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	conn, err := sql.Open(string(database.SQLite), filepath.Join(t.TempDir(), "faucet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same as the faucet, see openStore
+	conn.SetMaxOpenConns(1)
+	return conn
+}
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Store {
+		db, err := database.NewDB(openSQLite(t), database.SQLite, trace.Noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// TestSQLiteMigrations checks that every migration can be rolled back and
+// applied again
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	conn := openSQLite(t)
+	defer conn.Close()
+
+	migrator, err := database.NewMigrator(conn, database.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := migrator.Down(ctx, migrator.Latest()); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	applied, err := migrator.Up(ctx, 0)
+	if err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+	if len(applied) != migrator.Latest() {
+		t.Fatalf("applied %d migrations after Down, want %d", len(applied), migrator.Latest())
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrVoucherNotFound  = errors.New("voucher not found")
+	ErrVoucherRevoked   = errors.New("voucher revoked")
+	ErrVoucherExpired   = errors.New("voucher expired")
+	ErrVoucherExhausted = errors.New("voucher has no uses left")
+	ErrVoucherRedeemed  = errors.New("voucher already redeemed by this address")
+	ErrAPIKeyNotFound   = errors.New("API key not found")
+	ErrAirdropNotFound  = errors.New("airdrop not found")
+)
+
+// Store persists the faucet payouts and state. DB implements it on top of
+// PostgreSQL or SQLite and Memory keeps everything in memory. Every
+// implementation must pass the storetest conformance suite.
+type Store interface {
+	// SaveTransaction sets the timestamp of txn before saving it
+	SaveTransaction(ctx context.Context, txn *Transaction) error
+	// GetTransaction returns sql.ErrNoRows if txID is unknown
+	GetTransaction(ctx context.Context, txID string) (*Transaction, error)
+	GetAllTransactions(ctx context.Context) ([]Transaction, error)
+	// SearchTransactions returns the transactions matching filter, the most
+	// recent first
+	SearchTransactions(ctx context.Context, filter *TransactionFilter) ([]Transaction, error)
+	// GetStats aggregates the payouts of the native asset on chainID, or on
+	// every chain if chainID is empty
+	GetStats(ctx context.Context, chainID string) (*Stats, error)
+	// GetAdminPaidSince returns the amount of the native asset sent by
+	// admins on chainID since the given unix time, unconfirmed payouts
+	// included
+	GetAdminPaidSince(ctx context.Context, chainID string, since int64) (uint64, error)
+
+	AddDeniedAddress(ctx context.Context, address, reason string) error
+	RemoveDeniedAddress(ctx context.Context, address string) error
+	GetDeniedAddresses(ctx context.Context) ([]DeniedAddress, error)
+
+	// The pause state and refills are kept per chain
+	SavePauseState(ctx context.Context, chainID string, state *PauseState) error
+	GetPauseState(ctx context.Context, chainID string) (*PauseState, error)
+
+	SaveRefill(ctx context.Context, refill *Refill) error
+	// GetRefilledSince returns the amount of the refills on chainID since the
+	// given unix time that did not fail, unconfirmed ones included
+	GetRefilledSince(ctx context.Context, chainID string, since int64) (uint64, error)
+	GetRefillStats(ctx context.Context, chainID string) (*RefillStats, error)
+
+	// AddUsedSolution records that solutionHash was paid out for saltID until
+	// expiresAt. It returns false if the pair is already recorded, even if
+	// it expired but was not pruned yet.
+	AddUsedSolution(ctx context.Context, saltID, solutionHash string, expiresAt int64) (bool, error)
+	// RemoveUsedSolution forgets a pair whose payout failed
+	RemoveUsedSolution(ctx context.Context, saltID, solutionHash string) error
+	// PruneUsedSolutions removes the pairs that expired before now and
+	// returns how many were removed
+	PruneUsedSolutions(ctx context.Context, now int64) (int64, error)
+
+	// SaveVouchers sets the creation time of vouchers before saving them
+	SaveVouchers(ctx context.Context, vouchers []Voucher) error
+	// GetVouchers returns the vouchers of batchID ordered by code
+	GetVouchers(ctx context.Context, batchID string) ([]Voucher, error)
+	// RevokeVouchers revokes the vouchers of batchID, or the voucher code if
+	// batchID is empty, and returns how many were revoked
+	RevokeVouchers(ctx context.Context, batchID, code string) (int64, error)
+	// RedeemVoucher uses the voucher code of chainID once for address and
+	// records the redemption. It returns ErrVoucherNotFound,
+	// ErrVoucherRevoked, ErrVoucherExpired, ErrVoucherExhausted or
+	// ErrVoucherRedeemed if the voucher cannot be redeemed at now.
+	RedeemVoucher(ctx context.Context, chainID, code, address string, now int64) (*Voucher, error)
+	// CompleteRedemption records the payout of a redemption
+	CompleteRedemption(ctx context.Context, code, address, txID string) error
+	// ReleaseRedemption forgets a redemption whose payout failed, giving the
+	// use back to the voucher
+	ReleaseRedemption(ctx context.Context, code, address string) error
+
+	// SaveAPIKey sets the creation time of key before saving it
+	SaveAPIKey(ctx context.Context, key *APIKey) error
+	// GetAPIKeyByHash returns ErrAPIKeyNotFound if no key has keyHash, even
+	// a revoked one
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	// GetAPIKeys returns every key, the oldest first
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey returns false if id is unknown or already revoked
+	RevokeAPIKey(ctx context.Context, id string) (bool, error)
+	// UseAPIKey counts a request for amount on day against the daily quotas
+	// of key. It returns false, counting nothing, if a quota would be
+	// exceeded.
+	UseAPIKey(ctx context.Context, key *APIKey, day int64, amount uint64) (bool, error)
+	// ReleaseAPIKeyUse takes back a request whose payout failed
+	ReleaseAPIKeyUse(ctx context.Context, id string, day int64, amount uint64) error
+	// GetAPIKeyUsage returns the usage of the key id, or of every key if
+	// empty, since the given day, the most recent first
+	GetAPIKeyUsage(ctx context.Context, id string, since int64) ([]APIKeyUsage, error)
+
+	// SaveAirdrop sets the creation time of airdrop before saving it along
+	// with its rows
+	SaveAirdrop(ctx context.Context, airdrop *Airdrop, rows []AirdropRow) error
+	// GetAirdrop returns ErrAirdropNotFound if id is unknown
+	GetAirdrop(ctx context.Context, id string) (*Airdrop, error)
+	// GetAirdropRows returns up to limit rows of the airdrop id with status,
+	// or with any status if empty, ordered by line. A zero limit returns
+	// every row.
+	GetAirdropRows(ctx context.Context, id, status string, limit int) ([]AirdropRow, error)
+	// UpdateAirdropRow saves the status, transaction, error and update time
+	// of row if its status is still from. It returns false otherwise, so
+	// concurrent senders never send the same row twice.
+	UpdateAirdropRow(ctx context.Context, row *AirdropRow, from string) (bool, error)
+
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// Transaction is a payout along with what is known about the request that
+// triggered it
+type Transaction struct {
+	TxID        string `json:"txID"`
+	ChainID     string `json:"chainID"` // empty if recorded before chain IDs
+	Destination string `json:"destination"`
+	Amount      uint64 `json:"amount"`
+	Asset       string `json:"asset,omitempty"`    // asset ID, empty for the native asset
+	APIKeyID    string `json:"apiKeyID,omitempty"` // key that claimed the payout, if any
+	Admin       string `json:"admin,omitempty"`    // name of the admin key that sent the payout, if any
+	Reason      string `json:"reason,omitempty"`   // why the admin sent the payout
+	Timestamp   int64  `json:"timestamp"`
+
+	Fee            uint64 `json:"fee"` // max fee of the transaction
+	Difficulty     uint16 `json:"difficulty,omitempty"`
+	SaltID         string `json:"saltID,omitempty"`
+	SolutionHash   string `json:"solutionHash,omitempty"`
+	ClientIP       string `json:"clientIP,omitempty"`
+	UserAgent      string `json:"userAgent,omitempty"`
+	RPCEndpoint    string `json:"rpcEndpoint,omitempty"`
+	ProcessingTime int64  `json:"processingTime"` // milliseconds
+	// Unconfirmed is set on payouts whose transaction was sent but not
+	// confirmed, which may have been accepted
+	Unconfirmed bool `json:"unconfirmed,omitempty"`
+}
+
+// TransactionFilter selects transactions. Zero fields match every
+// transaction.
+type TransactionFilter struct {
+	TxID              string `json:"txID,omitempty"`
+	ChainID           string `json:"chainID,omitempty"`
+	Destination       string `json:"destination,omitempty"`
+	APIKeyID          string `json:"apiKeyID,omitempty"`
+	Admin             string `json:"admin,omitempty"`
+	Difficulty        uint16 `json:"difficulty,omitempty"`
+	SaltID            string `json:"saltID,omitempty"`
+	SolutionHash      string `json:"solutionHash,omitempty"`
+	ClientIP          string `json:"clientIP,omitempty"`
+	UserAgent         string `json:"userAgent,omitempty"` // case-insensitive substring
+	RPCEndpoint       string `json:"rpcEndpoint,omitempty"`
+	MinFee            uint64 `json:"minFee,omitempty"`
+	MaxFee            uint64 `json:"maxFee,omitempty"`
+	MinProcessingTime int64  `json:"minProcessingTime,omitempty"` // milliseconds
+	MaxProcessingTime int64  `json:"maxProcessingTime,omitempty"` // milliseconds
+	Since             int64  `json:"since,omitempty"`             // unix seconds, inclusive
+	Until             int64  `json:"until,omitempty"`             // unix seconds, inclusive
+	Limit             int    `json:"limit,omitempty"`             // 0 for no limit
+}
+
+// matches reports whether txn is selected by f, ignoring the limit
+func (f *TransactionFilter) matches(txn *Transaction) bool {
+	switch {
+	case f.TxID != "" && txn.TxID != f.TxID,
+		f.ChainID != "" && txn.ChainID != f.ChainID,
+		f.Destination != "" && txn.Destination != f.Destination,
+		f.APIKeyID != "" && txn.APIKeyID != f.APIKeyID,
+		f.Admin != "" && txn.Admin != f.Admin,
+		f.Difficulty != 0 && txn.Difficulty != f.Difficulty,
+		f.SaltID != "" && txn.SaltID != f.SaltID,
+		f.SolutionHash != "" && txn.SolutionHash != f.SolutionHash,
+		f.ClientIP != "" && txn.ClientIP != f.ClientIP,
+		f.UserAgent != "" && !strings.Contains(strings.ToLower(txn.UserAgent), strings.ToLower(f.UserAgent)),
+		f.RPCEndpoint != "" && txn.RPCEndpoint != f.RPCEndpoint,
+		f.MinFee != 0 && txn.Fee < f.MinFee,
+		f.MaxFee != 0 && txn.Fee > f.MaxFee,
+		f.MinProcessingTime != 0 && txn.ProcessingTime < f.MinProcessingTime,
+		f.MaxProcessingTime != 0 && txn.ProcessingTime > f.MaxProcessingTime,
+		f.Since != 0 && txn.Timestamp < f.Since,
+		f.Until != 0 && txn.Timestamp > f.Until:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stats aggregates the payouts recorded in the transactions table
+type Stats struct {
+	TotalTransactions   uint64 `json:"totalTransactions"`
+	TotalAmount         uint64 `json:"totalAmount"`
+	UniqueDestinations  uint64 `json:"uniqueDestinations"`
+	Last24hTransactions uint64 `json:"last24hTransactions"`
+	Last24hAmount       uint64 `json:"last24hAmount"`
+	LastTimestamp       int64  `json:"lastTimestamp"`
+}
+
+// Refill statuses
+const (
+	RefillSucceeded = "succeeded"
+	// RefillFailed is only recorded for refills that failed before their
+	// transaction was sent
+	RefillFailed = "failed"
+	// RefillUnconfirmed refills were sent but not confirmed and may have
+	// been accepted
+	RefillUnconfirmed = "unconfirmed"
+)
+
+// Refill is a transfer from the treasury to the faucet
+type Refill struct {
+	TxID      string `json:"txID,omitempty"`
+	ChainID   string `json:"chainID"`
+	Amount    uint64 `json:"amount"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// RefillStats aggregates the recorded refill attempts
+type RefillStats struct {
+	Attempts    uint64 `json:"attempts"`
+	Failed      uint64 `json:"failed"`
+	Unconfirmed uint64 `json:"unconfirmed"`
+	TotalAmount uint64 `json:"totalAmount"` // of the succeeded refills
+	// Last24hAmount counts the unconfirmed refills too, like the daily cap
+	Last24hAmount uint64 `json:"last24hAmount"`
+	LastTimestamp int64  `json:"lastTimestamp"`
+}
+
+// DeniedAddress is an address that is refused payouts
+type DeniedAddress struct {
+	Address   string `json:"address"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// PauseState is the persisted maintenance state of the faucet on a chain
+type PauseState struct {
+	Paused    bool   `json:"paused"`
+	Message   string `json:"message"`
+	ResumeAt  int64  `json:"resumeAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// Voucher is a code that pays Amount of Asset to the addresses redeeming it,
+// once per address and up to MaxUses times in total
+type Voucher struct {
+	Code      string `json:"code"`
+	BatchID   string `json:"batchID"`
+	ChainID   string `json:"chainID"`
+	Asset     string `json:"asset,omitempty"` // asset ID, empty for the native asset
+	Amount    uint64 `json:"amount"`
+	MaxUses   uint32 `json:"maxUses"`
+	Uses      uint32 `json:"uses"`
+	ExpiresAt int64  `json:"expiresAt"` // 0 if it never expires
+	RevokedAt int64  `json:"revokedAt"` // 0 if it is not revoked
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// redeemError returns why v cannot be redeemed by an address at now, given
+// whether that address already redeemed it
+func (v *Voucher) redeemError(now int64, redeemed bool) error {
+	switch {
+	case v.RevokedAt != 0:
+		return ErrVoucherRevoked
+	case redeemed:
+		return ErrVoucherRedeemed
+	case v.ExpiresAt != 0 && v.ExpiresAt <= now:
+		return ErrVoucherExpired
+	case v.Uses >= v.MaxUses:
+		return ErrVoucherExhausted
+	default:
+		return nil
+	}
+}
+
+// APIKey lets a trusted client claim funds without solving a challenge,
+// within daily quotas. Only a hash of the key itself is stored.
+type APIKey struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	KeyHash string `json:"-"` // hex SHA-256 of the key
+
+	DailyAmount   uint64 `json:"dailyAmount"`   // base units across assets, 0 for no limit
+	DailyRequests uint32 `json:"dailyRequests"` // 0 for no limit
+	// AllowedAssets are the asset IDs, or NAI for the native asset, the key
+	// may claim. Only the native asset if empty.
+	AllowedAssets []string `json:"allowedAssets,omitempty"`
+	// AllowedDestinations are the addresses the key may pay, any if empty
+	AllowedDestinations []string `json:"allowedDestinations,omitempty"`
+
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"`
+	RevokedAt int64  `json:"revokedAt"` // 0 if it is not revoked
+}
+
+// APIKeyUsage is the usage of a key on a UTC day, counted in days since the
+// unix epoch
+type APIKeyUsage struct {
+	KeyID    string `json:"keyID"`
+	Day      int64  `json:"day"`
+	Requests uint32 `json:"requests"`
+	Amount   uint64 `json:"amount"`
+}
+
+// allows reports whether a request for amount fits in the daily quotas of k
+// given the usage of the day so far
+func (k *APIKey) allows(usage *APIKeyUsage, amount uint64) bool {
+	switch {
+	case k.DailyRequests != 0 && usage.Requests >= k.DailyRequests:
+		return false
+	case k.DailyAmount != 0 && (amount > k.DailyAmount || usage.Amount > k.DailyAmount-amount):
+		return false
+	default:
+		return true
+	}
+}
+
+// joinList and splitList store the lists of an API key in a single column
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// Airdrop row statuses
+const (
+	AirdropPending = "pending"
+	AirdropSending = "sending"
+	AirdropSent    = "sent"
+	AirdropFailed  = "failed"
+	// AirdropUnconfirmed rows may have been paid: their transaction was
+	// sent but not confirmed, or they were interrupted while sending. They
+	// are never sent again until resolved as sent or failed.
+	AirdropUnconfirmed = "unconfirmed"
+)
+
+// Airdrop is a list of payouts of Asset sent by the admins in batches
+type Airdrop struct {
+	ID        string `json:"id"`
+	ChainID   string `json:"chainID"`
+	Asset     string `json:"asset,omitempty"` // asset ID, empty for the native asset
+	Name      string `json:"name"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// AirdropRow is the payout of a line of an airdrop list, numbered from 1
+type AirdropRow struct {
+	AirdropID string `json:"airdropID"`
+	Line      int    `json:"line"`
+	Address   string `json:"address"`
+	Amount    uint64 `json:"amount"`
+	Status    string `json:"status"`
+	TxID      string `json:"txID,omitempty"`
+	Error     string `json:"error,omitempty"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// AirdropSummary counts the rows of an airdrop by status
+type AirdropSummary struct {
+	Rows        int    `json:"rows"`
+	Pending     int    `json:"pending"`
+	Sending     int    `json:"sending"`
+	Sent        int    `json:"sent"`
+	Failed      int    `json:"failed"`
+	Unconfirmed int    `json:"unconfirmed"`
+	SentAmount  uint64 `json:"sentAmount"`
+}
+
+// SummarizeAirdrop counts rows by status
+func SummarizeAirdrop(rows []AirdropRow) AirdropSummary {
+	summary := AirdropSummary{Rows: len(rows)}
+	for _, row := range rows {
+		switch row.Status {
+		case AirdropPending:
+			summary.Pending++
+		case AirdropSending:
+			summary.Sending++
+		case AirdropSent:
+			summary.Sent++
+			summary.SentAmount += row.Amount
+		case AirdropFailed:
+			summary.Failed++
+		case AirdropUnconfirmed:
+			summary.Unconfirmed++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,714 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package storetest is the conformance suite every database.Store
+// implementation must pass
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nuklai/nuklai-faucet/database"
+)
+
+// Run runs the conformance suite against the stores returned by newStore.
+// Every test gets a new empty store.
+func Run(t *testing.T, newStore func(t *testing.T) database.Store) {
+	tests := []struct {
+		name string
+		f    func(*testing.T, database.Store)
+	}{
+		{"Transactions", testTransactions},
+		{"SearchTransactions", testSearchTransactions},
+		{"Stats", testStats},
+		{"DeniedAddresses", testDeniedAddresses},
+		{"PauseState", testPauseState},
+		{"Refills", testRefills},
+		{"UsedSolutions", testUsedSolutions},
+		{"Vouchers", testVouchers},
+		{"APIKeys", testAPIKeys},
+		{"Airdrops", testAirdrops},
+		{"AdminPayouts", testAdminPayouts},
+		{"Ping", testPing},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newStore(t)
+			t.Cleanup(store.Close)
+			test.f(t, store)
+		})
+	}
+}
+
+func testTransactions(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	if _, err := store.GetTransaction(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetTransaction of an unknown txID returned %v, want sql.ErrNoRows", err)
+	}
+	all, err := store.GetAllTransactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 0 {
+		t.Fatalf("new store has %d transactions", len(all))
+	}
+
+	before := time.Now().Unix()
+	mustSaveTransaction(t, store, "tx1", "dest1", 10)
+	mustSaveTransaction(t, store, "tx2", "dest2", 20)
+
+	txn, err := store.GetTransaction(ctx, "tx2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.TxID != "tx2" || txn.Destination != "dest2" || txn.Amount != 20 {
+		t.Fatalf("GetTransaction returned %+v", txn)
+	}
+	if txn.Timestamp < before {
+		t.Fatalf("transaction timestamp %d is before the save at %d", txn.Timestamp, before)
+	}
+
+	all, err = store.GetAllTransactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("GetAllTransactions returned %d transactions, want 2", len(all))
+	}
+}
+
+func testSearchTransactions(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	// Timestamps have a one second resolution, wait between saves so the
+	// order is deterministic
+	saved := []*database.Transaction{
+		{TxID: "tx1", ChainID: "chain1", Destination: "dest1", Amount: 10, Fee: 1, Difficulty: 1, SaltID: "salt1", SolutionHash: "sol1",
+			ClientIP: "10.0.0.1", UserAgent: "Mozilla/5.0 Firefox", RPCEndpoint: "http://rpc1", ProcessingTime: 100},
+		{TxID: "tx2", ChainID: "chain2", Destination: "dest2", Amount: 20, Admin: "alice", Reason: "support ticket", Fee: 2, Difficulty: 2, SaltID: "salt1", SolutionHash: "sol2",
+			ClientIP: "10.0.0.2", UserAgent: "curl/8.0 100%_done", RPCEndpoint: "http://rpc2", ProcessingTime: 200},
+		{TxID: "tx3", ChainID: "chain1", Destination: "dest1", Amount: 30, Fee: 3, Difficulty: 2, SaltID: "salt2", SolutionHash: "sol3",
+			ClientIP: "10.0.0.1", UserAgent: "faucet-cli", RPCEndpoint: "http://rpc1", ProcessingTime: 300},
+	}
+	for i, txn := range saved {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		if err := store.SaveTransaction(ctx, txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txn, err := store.GetTransaction(ctx, "tx2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *txn != *saved[1] {
+		t.Fatalf("GetTransaction returned %+v, want %+v", txn, saved[1])
+	}
+
+	tests := []struct {
+		filter database.TransactionFilter
+		want   []string
+	}{
+		{database.TransactionFilter{}, []string{"tx3", "tx2", "tx1"}},
+		{database.TransactionFilter{Limit: 2}, []string{"tx3", "tx2"}},
+		{database.TransactionFilter{TxID: "tx2"}, []string{"tx2"}},
+		{database.TransactionFilter{ChainID: "chain1"}, []string{"tx3", "tx1"}},
+		{database.TransactionFilter{Destination: "dest1"}, []string{"tx3", "tx1"}},
+		{database.TransactionFilter{Destination: "dest1", Limit: 1}, []string{"tx3"}},
+		{database.TransactionFilter{Destination: "dest3"}, nil},
+		{database.TransactionFilter{Difficulty: 2}, []string{"tx3", "tx2"}},
+		{database.TransactionFilter{SaltID: "salt1"}, []string{"tx2", "tx1"}},
+		{database.TransactionFilter{SolutionHash: "sol3"}, []string{"tx3"}},
+		{database.TransactionFilter{Admin: "alice"}, []string{"tx2"}},
+		{database.TransactionFilter{ClientIP: "10.0.0.1"}, []string{"tx3", "tx1"}},
+		{database.TransactionFilter{UserAgent: "FIREFOX"}, []string{"tx1"}},
+		{database.TransactionFilter{UserAgent: "%_"}, []string{"tx2"}},
+		{database.TransactionFilter{UserAgent: "mozilla_5"}, nil},
+		{database.TransactionFilter{RPCEndpoint: "http://rpc2"}, []string{"tx2"}},
+		{database.TransactionFilter{MinFee: 2}, []string{"tx3", "tx2"}},
+		{database.TransactionFilter{MaxFee: 2}, []string{"tx2", "tx1"}},
+		{database.TransactionFilter{MinProcessingTime: 150, MaxProcessingTime: 250}, []string{"tx2"}},
+		{database.TransactionFilter{Since: saved[1].Timestamp}, []string{"tx3", "tx2"}},
+		{database.TransactionFilter{Until: saved[1].Timestamp}, []string{"tx2", "tx1"}},
+		{database.TransactionFilter{ClientIP: "10.0.0.1", Difficulty: 1}, []string{"tx1"}},
+	}
+	for _, test := range tests {
+		transactions, err := store.SearchTransactions(ctx, &test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, txn := range transactions {
+			got = append(got, txn.TxID)
+		}
+		if !equal(got, test.want) {
+			t.Errorf("SearchTransactions(%+v) returned %v, want %v", test.filter, got, test.want)
+		}
+	}
+}
+
+func testStats(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	stats, err := store.GetStats(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (database.Stats{}) {
+		t.Fatalf("new store has stats %+v", stats)
+	}
+
+	mustSaveTransaction(t, store, "tx1", "dest1", 10)
+	mustSaveTransaction(t, store, "tx2", "dest2", 20)
+	mustSaveTransaction(t, store, "tx3", "dest1", 30)
+	if err := store.SaveTransaction(ctx, &database.Transaction{TxID: "tx4", ChainID: "chain2", Destination: "dest1", Amount: 40}); err != nil {
+		t.Fatal(err)
+	}
+	// Payouts of other assets are not counted
+	if err := store.SaveTransaction(ctx, &database.Transaction{TxID: "tx5", Destination: "dest3", Amount: 50, Asset: "asset1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = store.GetStats(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalTransactions != 4 || stats.TotalAmount != 100 || stats.UniqueDestinations != 2 {
+		t.Fatalf("GetStats returned %+v", stats)
+	}
+	if stats.Last24hTransactions != 4 || stats.Last24hAmount != 100 {
+		t.Fatalf("GetStats returned %+v", stats)
+	}
+	if stats.LastTimestamp == 0 {
+		t.Fatal("GetStats returned no last timestamp")
+	}
+
+	stats, err = store.GetStats(ctx, "chain2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalTransactions != 1 || stats.TotalAmount != 40 || stats.UniqueDestinations != 1 || stats.Last24hAmount != 40 {
+		t.Fatalf("GetStats of a chain returned %+v", stats)
+	}
+}
+
+func testDeniedAddresses(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	denied, err := store.GetDeniedAddresses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denied) != 0 {
+		t.Fatalf("new store has %d denied addresses", len(denied))
+	}
+
+	if err := store.AddDeniedAddress(ctx, "addr1", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddDeniedAddress(ctx, "addr2", "abuse"); err != nil {
+		t.Fatal(err)
+	}
+	// Denying an address again updates its reason
+	if err := store.AddDeniedAddress(ctx, "addr1", "bot"); err != nil {
+		t.Fatal(err)
+	}
+	denied, err = store.GetDeniedAddresses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := map[string]string{}
+	for _, d := range denied {
+		reasons[d.Address] = d.Reason
+	}
+	if len(denied) != 2 || reasons["addr1"] != "bot" || reasons["addr2"] != "abuse" {
+		t.Fatalf("GetDeniedAddresses returned %+v", denied)
+	}
+
+	if err := store.RemoveDeniedAddress(ctx, "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	// Removing an address that is not denied is not an error
+	if err := store.RemoveDeniedAddress(ctx, "addr3"); err != nil {
+		t.Fatal(err)
+	}
+	denied, err = store.GetDeniedAddresses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denied) != 1 || denied[0].Address != "addr2" {
+		t.Fatalf("GetDeniedAddresses returned %+v", denied)
+	}
+}
+
+func testPauseState(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	state, err := store.GetPauseState(ctx, "chain1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *state != (database.PauseState{}) {
+		t.Fatalf("new store has pause state %+v", state)
+	}
+
+	resumeAt := time.Now().Add(time.Hour).Unix()
+	saved := &database.PauseState{Paused: true, Message: "maintenance", ResumeAt: resumeAt}
+	if err := store.SavePauseState(ctx, "chain1", saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.UpdatedAt == 0 {
+		t.Fatal("SavePauseState did not set the update time")
+	}
+	state, err = store.GetPauseState(ctx, "chain1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *state != *saved {
+		t.Fatalf("GetPauseState returned %+v, want %+v", state, saved)
+	}
+	state, err = store.GetPauseState(ctx, "chain2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *state != (database.PauseState{}) {
+		t.Fatalf("GetPauseState of another chain returned %+v", state)
+	}
+
+	if err := store.SavePauseState(ctx, "chain1", &database.PauseState{}); err != nil {
+		t.Fatal(err)
+	}
+	state, err = store.GetPauseState(ctx, "chain1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Paused || state.Message != "" || state.ResumeAt != 0 {
+		t.Fatalf("GetPauseState returned %+v after resuming", state)
+	}
+}
+
+func testRefills(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	stats, err := store.GetRefillStats(ctx, "chain1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (database.RefillStats{}) {
+		t.Fatalf("new store has refill stats %+v", stats)
+	}
+
+	since := time.Now().Add(-time.Minute).Unix()
+	refills := []*database.Refill{
+		{TxID: "tx1", ChainID: "chain1", Amount: 100, Status: database.RefillSucceeded},
+		{ChainID: "chain1", Amount: 50, Status: database.RefillFailed, Error: "insufficient funds"},
+		{TxID: "tx2", ChainID: "chain1", Amount: 200, Status: database.RefillSucceeded},
+		{TxID: "tx3", ChainID: "chain2", Amount: 400, Status: database.RefillSucceeded},
+		{TxID: "tx4", ChainID: "chain1", Amount: 800, Status: database.RefillUnconfirmed, Error: "connection closed"},
+	}
+	for _, refill := range refills {
+		if err := store.SaveRefill(ctx, refill); err != nil {
+			t.Fatal(err)
+		}
+		if refill.Timestamp == 0 {
+			t.Fatal("SaveRefill did not set the timestamp")
+		}
+	}
+
+	amount, err := store.GetRefilledSince(ctx, "chain1", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 1100 {
+		t.Fatalf("GetRefilledSince returned %d, want 1100", amount)
+	}
+	amount, err = store.GetRefilledSince(ctx, "chain1", time.Now().Add(time.Minute).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 0 {
+		t.Fatalf("GetRefilledSince of the future returned %d, want 0", amount)
+	}
+
+	stats, err = store.GetRefillStats(ctx, "chain1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Attempts != 4 || stats.Failed != 1 || stats.Unconfirmed != 1 || stats.TotalAmount != 300 || stats.Last24hAmount != 1100 {
+		t.Fatalf("GetRefillStats returned %+v", stats)
+	}
+	if stats.LastTimestamp == 0 {
+		t.Fatal("GetRefillStats returned no last timestamp")
+	}
+}
+
+func testUsedSolutions(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	add := func(saltID, solutionHash string, expiresAt int64, want bool) {
+		t.Helper()
+		added, err := store.AddUsedSolution(ctx, saltID, solutionHash, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Fatalf("AddUsedSolution(%q, %q) returned %t, want %t", saltID, solutionHash, added, want)
+		}
+	}
+	add("salt1", "sol1", 100, true)
+	add("salt1", "sol1", 200, false)
+	// The same solution to another salt is a different pair
+	add("salt2", "sol1", 200, true)
+	add("salt1", "sol2", 300, true)
+
+	if err := store.RemoveUsedSolution(ctx, "salt1", "sol2"); err != nil {
+		t.Fatal(err)
+	}
+	add("salt1", "sol2", 300, true)
+
+	pruned, err := store.PruneUsedSolutions(ctx, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("PruneUsedSolutions pruned %d solutions, want 1", pruned)
+	}
+	add("salt1", "sol1", 400, true)
+	add("salt2", "sol1", 400, false)
+}
+
+func testVouchers(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	vouchers := []database.Voucher{
+		{Code: "B", BatchID: "batch1", ChainID: "chain1", Amount: 10, MaxUses: 2, CreatedBy: "admin"},
+		{Code: "A", BatchID: "batch1", ChainID: "chain1", Asset: "asset1", Amount: 20, MaxUses: 1, ExpiresAt: 1000},
+		{Code: "C", BatchID: "batch2", ChainID: "chain1", Amount: 30, MaxUses: 1},
+	}
+	if err := store.SaveVouchers(ctx, vouchers); err != nil {
+		t.Fatal(err)
+	}
+	if vouchers[0].CreatedAt == 0 {
+		t.Fatal("SaveVouchers did not set the creation time")
+	}
+	if err := store.SaveVouchers(ctx, []database.Voucher{{Code: "A", BatchID: "batch3", MaxUses: 1}}); err == nil {
+		t.Fatal("SaveVouchers saved a duplicate code")
+	}
+
+	got, err := store.GetVouchers(ctx, "batch1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != vouchers[1] || got[1] != vouchers[0] {
+		t.Fatalf("GetVouchers returned %+v", got)
+	}
+
+	redeem := func(code, address string, now int64, want error) {
+		t.Helper()
+		v, err := store.RedeemVoucher(ctx, "chain1", code, address, now)
+		if !errors.Is(err, want) {
+			t.Fatalf("RedeemVoucher(%q, %q) returned %v, want %v", code, address, err, want)
+		}
+		if err == nil && (v.Code != code || v.Uses == 0) {
+			t.Fatalf("RedeemVoucher(%q, %q) returned %+v", code, address, v)
+		}
+	}
+	redeem("B", "addr1", 500, nil)
+	redeem("B", "addr1", 500, database.ErrVoucherRedeemed)
+	redeem("B", "addr2", 500, nil)
+	redeem("B", "addr3", 500, database.ErrVoucherExhausted)
+	redeem("A", "addr1", 1000, database.ErrVoucherExpired)
+	redeem("unknown", "addr1", 500, database.ErrVoucherNotFound)
+	if _, err := store.RedeemVoucher(ctx, "chain2", "C", "addr1", 500); !errors.Is(err, database.ErrVoucherNotFound) {
+		t.Fatalf("RedeemVoucher on another chain returned %v", err)
+	}
+
+	// A failed payout gives the use back, a completed one keeps it
+	if err := store.CompleteRedemption(ctx, "B", "addr1", "tx1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReleaseRedemption(ctx, "B", "addr1"); err != nil {
+		t.Fatal(err)
+	}
+	redeem("B", "addr3", 500, database.ErrVoucherExhausted)
+	if err := store.ReleaseRedemption(ctx, "B", "addr2"); err != nil {
+		t.Fatal(err)
+	}
+	redeem("B", "addr3", 500, nil)
+
+	revoked, err := store.RevokeVouchers(ctx, "batch1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 2 {
+		t.Fatalf("RevokeVouchers revoked %d vouchers, want 2", revoked)
+	}
+	redeem("A", "addr2", 500, database.ErrVoucherRevoked)
+	if revoked, err = store.RevokeVouchers(ctx, "", "C"); err != nil || revoked != 1 {
+		t.Fatalf("RevokeVouchers of a code returned %d, %v", revoked, err)
+	}
+	if revoked, err = store.RevokeVouchers(ctx, "", "C"); err != nil || revoked != 0 {
+		t.Fatalf("RevokeVouchers of a revoked code returned %d, %v", revoked, err)
+	}
+	got, err = store.GetVouchers(ctx, "batch1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].RevokedAt == 0 || got[1].Uses != 2 {
+		t.Fatalf("GetVouchers returned %+v after revoking", got)
+	}
+}
+
+func testAPIKeys(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	key := &database.APIKey{
+		ID:                  "key1",
+		Name:                "ci",
+		KeyHash:             "hash1",
+		DailyAmount:         100,
+		DailyRequests:       3,
+		AllowedAssets:       []string{"NAI", "asset1"},
+		AllowedDestinations: []string{"dest1"},
+		CreatedBy:           "admin",
+	}
+	if err := store.SaveAPIKey(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if key.CreatedAt == 0 {
+		t.Fatal("SaveAPIKey did not set the creation time")
+	}
+	if err := store.SaveAPIKey(ctx, &database.APIKey{ID: "key2", Name: "other", KeyHash: "hash1"}); err == nil {
+		t.Fatal("SaveAPIKey saved a duplicate hash")
+	}
+	if err := store.SaveAPIKey(ctx, &database.APIKey{ID: "key2", Name: "unlimited", KeyHash: "hash2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetAPIKeyByHash(ctx, "hash1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "key1" || got.DailyAmount != 100 || !equal(got.AllowedAssets, key.AllowedAssets) || !equal(got.AllowedDestinations, key.AllowedDestinations) {
+		t.Fatalf("GetAPIKeyByHash returned %+v", got)
+	}
+	if _, err := store.GetAPIKeyByHash(ctx, "unknown"); !errors.Is(err, database.ErrAPIKeyNotFound) {
+		t.Fatalf("GetAPIKeyByHash of an unknown hash returned %v", err)
+	}
+	keys, err := store.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "key1" || keys[1].ID != "key2" || keys[1].AllowedAssets != nil {
+		t.Fatalf("GetAPIKeys returned %+v", keys)
+	}
+
+	use := func(key *database.APIKey, day int64, amount uint64, want bool) {
+		t.Helper()
+		used, err := store.UseAPIKey(ctx, key, day, amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != want {
+			t.Fatalf("UseAPIKey(%s, %d, %d) returned %t, want %t", key.ID, day, amount, used, want)
+		}
+	}
+	use(key, 10, 101, false)
+	use(key, 10, 60, true)
+	use(key, 10, 50, false)
+	use(key, 10, 40, true)
+	use(key, 10, 0, true)
+	use(key, 10, 0, false)
+	// Quotas start over every day
+	use(key, 11, 100, true)
+	use(&keys[1], 10, 1_000_000, true)
+
+	if err := store.ReleaseAPIKeyUse(ctx, "key1", 10, 40); err != nil {
+		t.Fatal(err)
+	}
+	use(key, 10, 50, false)
+	use(key, 10, 40, true)
+
+	usage, err := store.GetAPIKeyUsage(ctx, "key1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []database.APIKeyUsage{{KeyID: "key1", Day: 11, Requests: 1, Amount: 100}, {KeyID: "key1", Day: 10, Requests: 3, Amount: 100}}
+	if len(usage) != 2 || usage[0] != want[0] || usage[1] != want[1] {
+		t.Fatalf("GetAPIKeyUsage returned %+v", usage)
+	}
+	if usage, err = store.GetAPIKeyUsage(ctx, "", 10); err != nil || len(usage) != 3 {
+		t.Fatalf("GetAPIKeyUsage of every key returned %+v, %v", usage, err)
+	}
+	if usage, err = store.GetAPIKeyUsage(ctx, "", 11); err != nil || len(usage) != 1 {
+		t.Fatalf("GetAPIKeyUsage since a day returned %+v, %v", usage, err)
+	}
+
+	revoked, err := store.RevokeAPIKey(ctx, "key1")
+	if err != nil || !revoked {
+		t.Fatalf("RevokeAPIKey returned %t, %v", revoked, err)
+	}
+	if revoked, err = store.RevokeAPIKey(ctx, "key1"); err != nil || revoked {
+		t.Fatalf("RevokeAPIKey of a revoked key returned %t, %v", revoked, err)
+	}
+	if got, err = store.GetAPIKeyByHash(ctx, "hash1"); err != nil || got.RevokedAt == 0 {
+		t.Fatalf("GetAPIKeyByHash of a revoked key returned %+v, %v", got, err)
+	}
+
+	if err := store.SaveTransaction(ctx, &database.Transaction{TxID: "tx1", Destination: "dest1", Amount: 10, APIKeyID: "key1"}); err != nil {
+		t.Fatal(err)
+	}
+	mustSaveTransaction(t, store, "tx2", "dest1", 10)
+	txs, err := store.SearchTransactions(ctx, &database.TransactionFilter{APIKeyID: "key1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].TxID != "tx1" || txs[0].APIKeyID != "key1" {
+		t.Fatalf("SearchTransactions by API key returned %+v", txs)
+	}
+}
+
+func testAirdrops(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	airdrop := &database.Airdrop{ID: "drop1", ChainID: "chain1", Name: "hackathon", CreatedBy: "admin"}
+	rows := []database.AirdropRow{
+		{AirdropID: "drop1", Line: 1, Address: "dest1", Amount: 10, Status: database.AirdropPending},
+		{AirdropID: "drop1", Line: 2, Address: "dest2", Amount: 20, Status: database.AirdropPending},
+		{AirdropID: "drop1", Line: 3, Address: "dest3", Amount: 30, Status: database.AirdropPending},
+	}
+	if err := store.SaveAirdrop(ctx, airdrop, rows); err != nil {
+		t.Fatal(err)
+	}
+	if airdrop.CreatedAt == 0 {
+		t.Fatal("SaveAirdrop did not set the creation time")
+	}
+	if err := store.SaveAirdrop(ctx, &database.Airdrop{ID: "drop1"}, nil); err == nil {
+		t.Fatal("SaveAirdrop saved a duplicate ID")
+	}
+	got, err := store.GetAirdrop(ctx, "drop1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *airdrop {
+		t.Fatalf("GetAirdrop returned %+v, want %+v", got, airdrop)
+	}
+	if _, err := store.GetAirdrop(ctx, "unknown"); !errors.Is(err, database.ErrAirdropNotFound) {
+		t.Fatalf("GetAirdrop of an unknown ID returned %v", err)
+	}
+
+	pending, err := store.GetAirdropRows(ctx, "drop1", database.AirdropPending, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Line != 1 || pending[1].Line != 2 {
+		t.Fatalf("GetAirdropRows returned %+v, want lines 1 and 2", pending)
+	}
+
+	row := pending[0]
+	row.Status, row.UpdatedAt = database.AirdropSending, 100
+	if ok, err := store.UpdateAirdropRow(ctx, &row, database.AirdropPending); err != nil || !ok {
+		t.Fatalf("UpdateAirdropRow of a pending row returned %v, %v", ok, err)
+	}
+	if ok, err := store.UpdateAirdropRow(ctx, &row, database.AirdropPending); err != nil || ok {
+		t.Fatalf("UpdateAirdropRow of a row no longer pending returned %v, %v", ok, err)
+	}
+	row.Status, row.TxID, row.UpdatedAt = database.AirdropSent, "tx1", 101
+	if ok, err := store.UpdateAirdropRow(ctx, &row, database.AirdropSending); err != nil || !ok {
+		t.Fatalf("UpdateAirdropRow of a sending row returned %v, %v", ok, err)
+	}
+	failed := pending[1]
+	failed.Status, failed.Error, failed.UpdatedAt = database.AirdropFailed, "insufficient funds", 102
+	if ok, err := store.UpdateAirdropRow(ctx, &failed, database.AirdropPending); err != nil || !ok {
+		t.Fatalf("UpdateAirdropRow of a pending row returned %v, %v", ok, err)
+	}
+
+	all, err := store.GetAirdropRows(ctx, "drop1", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0] != row || all[1] != failed || all[2] != rows[2] {
+		t.Fatalf("GetAirdropRows returned %+v", all)
+	}
+	summary := database.SummarizeAirdrop(all)
+	if summary != (database.AirdropSummary{Rows: 3, Pending: 1, Sent: 1, Failed: 1, SentAmount: 10}) {
+		t.Fatalf("SummarizeAirdrop returned %+v", summary)
+	}
+	if rows, err := store.GetAirdropRows(ctx, "unknown", "", 0); err != nil || len(rows) != 0 {
+		t.Fatalf("GetAirdropRows of an unknown airdrop returned %v, %v", rows, err)
+	}
+}
+
+func testAdminPayouts(t *testing.T, store database.Store) {
+	ctx := context.Background()
+
+	since := time.Now().Unix()
+	for _, txn := range []*database.Transaction{
+		{TxID: "tx1", ChainID: "chain1", Destination: "dest1", Amount: 10, Admin: "alice", Reason: "support"},
+		{TxID: "tx2", ChainID: "chain1", Destination: "dest1", Amount: 20, Admin: "bob", Reason: "support"},
+		{TxID: "tx3", ChainID: "chain1", Destination: "dest1", Amount: 40},
+		{TxID: "tx4", ChainID: "chain1", Destination: "dest1", Amount: 80, Asset: "asset1", Admin: "alice"},
+		{TxID: "tx5", ChainID: "chain2", Destination: "dest1", Amount: 160, Admin: "alice"},
+		{TxID: "tx6", ChainID: "chain1", Destination: "dest1", Amount: 320, Admin: "bob", Unconfirmed: true},
+	} {
+		if err := store.SaveTransaction(ctx, txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	amount, err := store.GetAdminPaidSince(ctx, "chain1", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 350 {
+		t.Fatalf("GetAdminPaidSince returned %d, want 350", amount)
+	}
+	got, err := store.GetTransaction(ctx, "tx6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Unconfirmed {
+		t.Fatal("unconfirmed payout saved as confirmed")
+	}
+	amount, err = store.GetAdminPaidSince(ctx, "chain1", time.Now().Add(time.Minute).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 0 {
+		t.Fatalf("GetAdminPaidSince of the future returned %d, want 0", amount)
+	}
+}
+
+func testPing(t *testing.T, store database.Store) {
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustSaveTransaction(t *testing.T, store database.Store, txID, destination string, amount uint64) {
+	t.Helper()
+
+	txn := &database.Transaction{TxID: txID, Destination: destination, Amount: amount}
+	if err := store.SaveTransaction(context.Background(), txn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// dotEnv loads the .env file into the environment without overriding the
+// variables the process was started with. It is loaded again on reload to
+// pick up changes to the file.
+type dotEnv struct {
+	set map[string]bool // variables set from the file
+}
+
+func (d *dotEnv) load() error {
+	env, err := godotenv.Read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for key := range d.set {
+		if _, ok := env[key]; !ok {
+			os.Unsetenv(key)
+			delete(d.set, key)
+		}
+	}
+	for key, value := range env {
+		if _, ok := os.LookupEnv(key); ok && !d.set[key] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		d.set[key] = true
+	}
+	return nil
+}
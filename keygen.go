@@ -0,0 +1,96 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"golang.org/x/term"
+
+	"github.com/nuklai/nuklai-faucet/keystore"
+)
+
+const keygenUsage = `Usage: nuklai-faucet keygen -out <file> [flags]
+
+Generates a private key, writes it to a new keystore file encrypted with a
+passphrase and prints its address. The passphrase is read from
+-passphrase-file, KEYSTORE_PASSPHRASE or prompted for, in that order.
+
+Flags:
+`
+
+// runKeygen implements the keygen subcommand, which creates the keystore of
+// a new faucet wallet
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "", "keystore file to create")
+	passphraseFile := fs.String("passphrase-file", "", "file holding the passphrase")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, keygenUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("-out is required")
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	key, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	if err := keystore.WriteFile(*out, key, passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("address:  %s\n", keystore.Address(key))
+	fmt.Printf("keystore: %s\n", *out)
+	fmt.Println("Set KEYSTORE_FILE and KEYSTORE_PASSPHRASE to use it as the faucet wallet")
+	return nil
+}
+
+// readPassphrase reads the passphrase of a new keystore from path, the
+// environment or a prompt
+func readPassphrase(path string) ([]byte, error) {
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(b, "\r\n"), nil
+	}
+	if passphrase, ok := os.LookupEnv("KEYSTORE_PASSPHRASE"); ok {
+		return []byte(passphrase), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no passphrase, set -passphrase-file or KEYSTORE_PASSPHRASE")
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	repeated, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, repeated) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
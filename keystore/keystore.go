@@ -0,0 +1,165 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package keystore stores ed25519 private keys in files encrypted with a
+// passphrase. The encryption key is derived from the passphrase with scrypt
+// and the private key is sealed with AES-256-GCM.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklaivm/auth"
+	"github.com/nuklai/nuklaivm/consts"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version = 1
+
+	kdfScrypt    = "scrypt"
+	cipherAESGCM = "aes-256-gcm"
+
+	// scrypt parameters of new keystores, deriving a key takes about 128 MiB
+	// of memory
+	scryptN      = 1 << 17
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 32
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+)
+
+// File is the JSON encoding of a keystore
+type File struct {
+	Version int `json:"version"`
+	// Address is the bech32 address of the key, authenticated but not
+	// encrypted so the file can be identified without the passphrase
+	Address string `json:"address"`
+	Crypto  Crypto `json:"crypto"`
+}
+
+type Crypto struct {
+	KDF        string       `json:"kdf"`
+	KDFParams  ScryptParams `json:"kdfparams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      []byte       `json:"nonce"`
+	Ciphertext []byte       `json:"ciphertext"`
+}
+
+type ScryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+// Address returns the bech32 address of key
+func Address(key ed25519.PrivateKey) string {
+	return codec.MustAddressBech32(consts.HRP, auth.NewED25519Address(key.PublicKey()))
+}
+
+// Encrypt returns the keystore of key, encrypted with passphrase
+func Encrypt(key ed25519.PrivateKey, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	f := File{
+		Version: version,
+		Address: Address(key),
+		Crypto: Crypto{
+			KDF:       kdfScrypt,
+			KDFParams: ScryptParams{N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, saltLen)},
+			Cipher:    cipherAESGCM,
+		},
+	}
+	if _, err := rand.Read(f.Crypto.KDFParams.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(&f.Crypto.KDFParams, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	f.Crypto.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Crypto.Nonce); err != nil {
+		return nil, err
+	}
+	f.Crypto.Ciphertext = aead.Seal(nil, f.Crypto.Nonce, key[:], []byte(f.Address))
+	return json.MarshalIndent(&f, "", "  ")
+}
+
+// Decrypt returns the private key of the keystore data
+func Decrypt(data []byte, passphrase []byte) (ed25519.PrivateKey, error) {
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return ed25519.EmptyPrivateKey, fmt.Errorf("invalid keystore: %w", err)
+	}
+	if f.Version != version {
+		return ed25519.EmptyPrivateKey, fmt.Errorf("unsupported keystore version %d", f.Version)
+	}
+	if f.Crypto.KDF != kdfScrypt || f.Crypto.Cipher != cipherAESGCM {
+		return ed25519.EmptyPrivateKey, fmt.Errorf("unsupported keystore kdf %q or cipher %q", f.Crypto.KDF, f.Crypto.Cipher)
+	}
+	aead, err := newAEAD(&f.Crypto.KDFParams, passphrase)
+	if err != nil {
+		return ed25519.EmptyPrivateKey, err
+	}
+	if len(f.Crypto.Nonce) != aead.NonceSize() {
+		return ed25519.EmptyPrivateKey, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, f.Crypto.Nonce, f.Crypto.Ciphertext, []byte(f.Address))
+	if err != nil || len(plaintext) != ed25519.PrivateKeyLen {
+		return ed25519.EmptyPrivateKey, ErrWrongPassphrase
+	}
+	return ed25519.PrivateKey(plaintext), nil
+}
+
+// WriteFile writes the keystore of key to path, which must not exist yet
+func WriteFile(path string, key ed25519.PrivateKey, passphrase []byte) error {
+	data, err := Encrypt(key, passphrase)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile returns the private key of the keystore at path
+func ReadFile(path string, passphrase []byte) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ed25519.EmptyPrivateKey, err
+	}
+	return Decrypt(data, passphrase)
+}
+
+func newAEAD(params *ScryptParams, passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore kdf params: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package keystore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklai-faucet/keystore"
+)
+
+var passphrase = []byte("correct horse battery staple")
+
+func writeKeystore(t *testing.T) (string, ed25519.PrivateKey) {
+	key, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := keystore.WriteFile(path, key, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	return path, key
+}
+
+func TestRoundTrip(t *testing.T) {
+	path, key := writeKeystore(t)
+
+	got, err := keystore.ReadFile(path, passphrase)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if got != key {
+		t.Fatal("ReadFile returned another key")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f keystore.File
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Address != keystore.Address(key) {
+		t.Fatalf("address = %s, want %s", f.Address, keystore.Address(key))
+	}
+
+	// An existing keystore is never overwritten
+	if err := keystore.WriteFile(path, key, passphrase); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("WriteFile over an existing file = %v, want %v", err, os.ErrExist)
+	}
+}
+
+func TestEmptyPassphrase(t *testing.T) {
+	key, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keystore.Encrypt(key, nil); !errors.Is(err, keystore.ErrEmptyPassphrase) {
+		t.Fatalf("Encrypt = %v, want %v", err, keystore.ErrEmptyPassphrase)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path, _ := writeKeystore(t)
+
+	if _, err := keystore.ReadFile(path, []byte("wrong")); !errors.Is(err, keystore.ErrWrongPassphrase) {
+		t.Fatalf("ReadFile = %v, want %v", err, keystore.ErrWrongPassphrase)
+	}
+}
+
+func TestCorruptedFile(t *testing.T) {
+	path, _ := writeKeystore(t)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(f *keystore.File)
+		// wrongPassphrase is set if the corruption is only detected when
+		// opening the ciphertext
+		wrongPassphrase bool
+	}{
+		{
+			name:            "ciphertext",
+			corrupt:         func(f *keystore.File) { f.Crypto.Ciphertext[0] ^= 1 },
+			wrongPassphrase: true,
+		},
+		{
+			name:            "address",
+			corrupt:         func(f *keystore.File) { f.Address += "x" },
+			wrongPassphrase: true,
+		},
+		{
+			name:            "nonce",
+			corrupt:         func(f *keystore.File) { f.Crypto.Nonce = f.Crypto.Nonce[1:] },
+			wrongPassphrase: true,
+		},
+		{
+			name:            "salt",
+			corrupt:         func(f *keystore.File) { f.Crypto.KDFParams.Salt[0] ^= 1 },
+			wrongPassphrase: true,
+		},
+		{
+			name:    "kdf params",
+			corrupt: func(f *keystore.File) { f.Crypto.KDFParams.N = 3 },
+		},
+		{
+			name:    "version",
+			corrupt: func(f *keystore.File) { f.Version = 2 },
+		},
+		{
+			name:    "cipher",
+			corrupt: func(f *keystore.File) { f.Crypto.Cipher = "aes-128-ctr" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f keystore.File
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(&f)
+			corrupted, err := json.Marshal(&f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = keystore.Decrypt(corrupted, passphrase)
+			if err == nil {
+				t.Fatal("Decrypt accepted a corrupted keystore")
+			}
+			if tt.wrongPassphrase && !errors.Is(err, keystore.ErrWrongPassphrase) {
+				t.Fatalf("Decrypt = %v, want %v", err, keystore.ErrWrongPassphrase)
+			}
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		if _, err := keystore.Decrypt(data[:len(data)/2], passphrase); err == nil {
+			t.Fatal("Decrypt accepted a truncated keystore")
+		}
+	})
+}
@@ -6,6 +6,8 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
@@ -14,17 +16,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ava-labs/avalanchego/trace"
 	"github.com/ava-labs/avalanchego/utils/logging"
-	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/server"
 	"github.com/ava-labs/hypersdk/utils"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/nuklai/nuklai-faucet/config"
+	"github.com/nuklai/nuklai-faucet/database"
 	"github.com/nuklai/nuklai-faucet/manager"
 	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	"github.com/nuklai/nuklai-faucet/signer"
 )
 
 var (
@@ -47,13 +52,142 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func main() {
-	err := godotenv.Overload() // Overload the environment variables with those from the .env file
+// LivenessHandler reports that the process is up and serving HTTP
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadinessHandler checks every dependency of the faucet and responds with a
+// per-component breakdown, with status 503 if any check fails
+func ReadinessHandler(m *manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := m.CheckReadiness(r.Context())
+		status := http.StatusOK
+		if !readiness.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, readiness)
+	}
+}
+
+// NetworksReadinessHandler checks every network of the faucet and responds
+// with the breakdown of each network, with status 503 if any is not ready
+func NetworksReadinessHandler(managers []*manager.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		networks := map[string]*manager.Readiness{}
+		status := http.StatusOK
+		for _, m := range managers {
+			readiness := m.CheckReadiness(r.Context())
+			if !readiness.Ready {
+				status = http.StatusServiceUnavailable
+			}
+			networks[m.Config().Network] = readiness
+		}
+		writeJSON(w, status, map[string]any{"ready": status == http.StatusOK, "networks": networks})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// openStore opens the Store selected by the configured database backend.
+// PostgreSQL is retried for a while since it often starts alongside the
+// faucet.
+func openStore(log logging.Logger, cfg *config.Config, tracer trace.Tracer) (database.Store, error) {
+	switch cfg.DatabaseBackend {
+	case config.DatabaseMemory:
+		log.Warn("Using the in-memory database, all data is lost on restart")
+		return database.NewMemory(), nil
+	case config.DatabaseSQLite:
+		db, err := sql.Open(string(database.SQLite), cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows a single writer, serialize access instead of failing
+		// with SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+		return database.NewDB(db, database.SQLite, tracer)
+	}
+
+	// Retry mechanism for PostgreSQL connection
+	var (
+		db  *sql.DB
+		err error
+	)
+	for i := 0; i < 10; i++ {
+		db, err = sql.Open(string(database.Postgres), cfg.PostgresDSN())
+		if err != nil {
+			log.Warn("Error opening database", zap.Error(err))
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		err = db.Ping()
+		if err == nil {
+			break
+		}
+		log.Warn("Database not ready, retrying...", zap.Error(err))
+		time.Sleep(5 * time.Second)
+	}
 	if err != nil {
+		return nil, err
+	}
+	return database.NewDB(db, database.Postgres, tracer)
+}
+
+const usage = `Usage: nuklai-faucet [flags] [command]
+
+Commands:
+  migrate   manage the database schema, see "nuklai-faucet migrate"
+  config    inspect the configuration, see "nuklai-faucet config"
+  keygen    create an encrypted keystore, see "nuklai-faucet keygen -help"
+
+Without a command the faucet is started. Settings are read from the -config
+file, then the environment, then the flags below, the last one set winning.
+
+Flags:
+`
+
+func main() {
+	// Variables from the .env file do not override the environment
+	dotenv := &dotEnv{set: map[string]bool{}}
+	if err := dotenv.load(); err != nil {
 		utils.Outf("{{red}}Error loading .env file{{/}}: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Loaded environment variables from .env file")
+
+	fs := flag.NewFlagSet("nuklai-faucet", flag.ExitOnError)
+	configFlags := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[1:])
+	args := fs.Args()
+
+	// A new key does not depend on the config
+	if len(args) > 0 && args[0] == "keygen" {
+		if err := runKeygen(args[1:]); err != nil {
+			utils.Outf("{{red}}error{{/}}: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config, err := configFlags.Load()
+	if err != nil {
+		utils.Outf("{{red}}invalid config{{/}}:\n%v\n", err)
+		os.Exit(1)
+	}
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(config, args[1:]); err != nil {
+			utils.Outf("{{red}}error{{/}}: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	logFactory := logging.NewFactory(logging.Config{
 		DisplayLevel: logging.Info,
@@ -65,24 +199,18 @@ func main() {
 	}
 	log := l
 	log.Info("Logger initialized")
+	log.Info("Config loaded", zap.String("file", configFlags.Path()))
 
-	// Load config from environment variables
-	config, err := config.LoadConfigFromEnv()
-	if err != nil {
-		fatal(log, "cannot load config from environment variables", zap.Error(err))
-	}
-	log.Info("Config loaded from environment variables")
-
-	// Create private key
-	if len(config.PrivateKeyBytes) == 0 {
-		priv, err := ed25519.GeneratePrivateKey()
-		if err != nil {
-			fatal(log, "cannot generate private key", zap.Error(err))
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			fs.Usage()
+			fatal(log, "unknown command", zap.String("command", args[0]))
+		}
+		if err := runMigrate(config, args[1:]); err != nil {
+			fatal(log, "migration failed", zap.Error(err))
 		}
-		config.PrivateKeyBytes = priv[:]
-		fatal(log, "private key should be set in .env file after generation")
+		return
 	}
-	log.Info("Private key generated")
 
 	// Create server
 	listenAddress := net.JoinHostPort(config.HTTPHost, fmt.Sprintf("%d", config.HTTPPort))
@@ -101,57 +229,100 @@ func main() {
 		IdleTimeout:  httpConfig.IdleTimeout,
 	}
 
-	// Add health check handler
+	// Add health check handlers
 	mux.HandleFunc("/health", HealthHandler)
-	log.Info("Health handler added")
+	mux.HandleFunc("/livez", LivenessHandler)
+	log.Info("Health handlers added")
 
-	// Retry mechanism for PostgreSQL connection
-	var db *sql.DB
-	for i := 0; i < 10; i++ {
-		connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			config.PostgresHost, config.PostgresPort, config.PostgresUser, config.PostgresPassword, config.PostgresDBName, config.PostgresSSLMode)
-		db, err = sql.Open("postgres", connStr)
-		if err != nil {
-			log.Warn("Error opening database", zap.Error(err))
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		err = db.Ping()
-		if err == nil {
-			break
-		}
-		log.Warn("Database not ready, retrying...", zap.Error(err))
-		time.Sleep(5 * time.Second)
+	// Add metrics handler
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collectors.NewGoCollector()); err != nil {
+		fatal(log, "cannot register go collector", zap.Error(err))
+	}
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		fatal(log, "cannot register process collector", zap.Error(err))
 	}
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	log.Info("Metrics handler added")
 
+	// Create tracer, a no-op unless tracing is enabled
+	tracer, err := trace.New(config.Tracing)
 	if err != nil {
-		fatal(log, "could not connect to the database", zap.Error(err))
+		fatal(log, "cannot create tracer", zap.Error(err))
 	}
-	log.Info("Database connection established")
+	defer tracer.Close()
+	log.Info("Tracer created", zap.Bool("enabled", config.Tracing.Enabled))
 
-	// Start manager with context handling
-	manager, err := manager.New(log, config, db)
+	store, err := openStore(log, config, tracer)
 	if err != nil {
-		fatal(log, "cannot create manager", zap.Error(err))
+		fatal(log, "could not connect to the database", zap.Error(err))
 	}
-	log.Info("Manager created")
+	log.Info("Database connection established", zap.String("backend", config.DatabaseBackend))
+	defer store.Close()
+
+	// Every network has its own manager served under /<network>, the first
+	// one is also served at the root
 	ctx, cancel := context.WithCancel(context.Background())
+	var managers []*manager.Manager
+	for i, networkConfig := range config.NetworkConfigs() {
+		network := networkConfig.Network
+		var networkRegistry prometheus.Registerer = registry
+		if network != "" {
+			networkRegistry = prometheus.WrapRegistererWith(prometheus.Labels{"network": network}, registry)
+		}
 
-	go func() {
-		log.Info("Starting manager")
-		if err := manager.Run(ctx); err != nil {
-			log.Error("Manager error", zap.Error(err))
+		// The faucet keys are held in process unless a remote signer is set
+		var s signer.Signer
+		if networkConfig.SignerSocket != "" {
+			s = signer.NewClient(networkConfig.SignerSocket)
+			log.Info("Using remote signer", zap.String("network", network), zap.String("socket", networkConfig.SignerSocket))
+		} else {
+			// The faucet checks the assets of vouchers, API keys and
+			// airdrops itself, an in-process policy would add nothing
+			s = signer.NewLocal(networkConfig.PrivateKeys(), signer.Policy{AnyAsset: true})
 		}
-	}()
 
-	// Add faucet handler
-	faucetServer := frpc.NewJSONRPCServer(manager)
-	handler, err := server.NewHandler(faucetServer, "faucet")
-	if err != nil {
-		fatal(log, "cannot create handler", zap.Error(err))
+		// Start manager with context handling
+		m, err := manager.New(log, networkConfig, s, store, networkRegistry, tracer)
+		if err != nil {
+			fatal(log, "cannot create manager", zap.String("network", network), zap.Error(err))
+		}
+		log.Info("Manager created", zap.String("network", network))
+		managers = append(managers, m)
+
+		go func() {
+			log.Info("Starting manager", zap.String("network", network))
+			if err := m.Run(ctx); err != nil {
+				log.Error("Manager error", zap.String("network", network), zap.Error(err))
+			}
+		}()
+
+		// Add faucet handler
+		faucetServer, err := frpc.NewJSONRPCServer(m, networkRegistry, tracer)
+		if err != nil {
+			fatal(log, "cannot create faucet server", zap.Error(err))
+		}
+		handler, err := server.NewHandler(faucetServer, "faucet")
+		if err != nil {
+			fatal(log, "cannot create handler", zap.Error(err))
+		}
+		tracingHandler := frpc.NewTracingHandler(tracer, "faucet", handler)
+		if network != "" {
+			prefix := "/" + network
+			mux.Handle(prefix+"/readyz", ReadinessHandler(m))
+			mux.Handle(prefix+"/", http.StripPrefix(prefix, tracingHandler))
+		}
+		if i == 0 {
+			mux.Handle("/", tracingHandler)
+		}
+		log.Info("Faucet handler added", zap.String("network", network))
 	}
-	mux.Handle("/", handler)
-	log.Info("Faucet handler added")
+	if len(managers) == 1 {
+		mux.Handle("/readyz", ReadinessHandler(managers[0]))
+	} else {
+		mux.Handle("/readyz", NetworksReadinessHandler(managers))
+	}
+	log.Info("Readiness handler added")
 
 	// Start server
 	sigs := make(chan os.Signal, 1)
@@ -162,6 +333,36 @@ func main() {
 		cancel() // this will signal the manager's run function to stop
 		_ = srv.Shutdown(ctx)
 	}()
+
+	// Reload the config on SIGHUP, the manager applies the settings that
+	// can change live
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	go func() {
+		for range hups {
+			log.Info("Reloading config", zap.String("file", configFlags.Path()))
+			if err := dotenv.load(); err != nil {
+				log.Error("Cannot reload .env file", zap.Error(err))
+				continue
+			}
+			next, err := configFlags.Load()
+			if err != nil {
+				log.Error("Cannot reload config", zap.Error(err))
+				continue
+			}
+			for _, m := range managers {
+				network := m.Config().Network
+				networkConfig := next.NetworkConfig(network)
+				if networkConfig == nil {
+					log.Error("Cannot reload removed network, restart required", zap.String("network", network))
+					continue
+				}
+				if err := m.Reload(networkConfig); err != nil {
+					log.Error("Cannot apply reloaded config", zap.String("network", network), zap.Error(err))
+				}
+			}
+		}
+	}()
 	log.Info("Server starting")
 
 	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
@@ -0,0 +1,100 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/utils"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+// SendFunds pays amount, or the configured amount if zero, to destination on
+// behalf of the admin key named admin, for instance to users who could not
+// solve the challenge. The payout goes through the same fee and balance
+// checks, pause and deny list as a claim and is recorded with admin and
+// reason. Admin payouts of the last 24 hours, including the unconfirmed
+// ones, must stay within ADMIN_DAILY_BUDGET.
+func (m *Manager) SendFunds(ctx context.Context, admin string, destination codec.Address, amount uint64, reason string) (ids.ID, uint64, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.SendFunds")
+	defer span.End()
+
+	if reason == "" {
+		return ids.Empty, 0, frpc.ErrInvalidArgument.WithDetail("a reason is required")
+	}
+	start := time.Now()
+	config := m.Config()
+	if amount == 0 {
+		amount = config.Amount
+	}
+	address := codec.MustAddressBech32(nconsts.HRP, destination)
+	m.l.RLock()
+	err := m.admit(destination)
+	m.l.RUnlock()
+	if err != nil {
+		m.log.Warn("Rejecting admin payout", zap.String("admin", admin), zap.String("address", address), zap.Error(err))
+		return ids.Empty, 0, err
+	}
+
+	// The budget is checked against the recorded payouts, so they are sent
+	// one at a time
+	m.adminL.Lock()
+	defer m.adminL.Unlock()
+
+	if config.AdminDailyBudget == 0 {
+		return ids.Empty, 0, frpc.ErrAdminBudgetExceeded.WithDetail("admin payouts are disabled, ADMIN_DAILY_BUDGET is 0")
+	}
+	chainID := m.ChainID().String()
+	paid, err := m.db.GetAdminPaidSince(ctx, chainID, start.Add(-24*time.Hour).Unix())
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	if paid > config.AdminDailyBudget || amount > config.AdminDailyBudget-paid {
+		var left uint64
+		if paid < config.AdminDailyBudget {
+			left = config.AdminDailyBudget - paid
+		}
+		m.log.Warn("Admin payout budget exceeded",
+			zap.String("admin", admin),
+			zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
+			zap.String("left", utils.FormatBalance(left, nconsts.Decimals)),
+		)
+		return ids.Empty, 0, frpc.ErrAdminBudgetExceeded.WithDetail(fmt.Sprintf("%s %s left in the last 24 hours", utils.FormatBalance(left, nconsts.Decimals), nconsts.Symbol))
+	}
+
+	txID, payout, err := m.sendFundsRetry(ctx, destination, ids.Empty, amount)
+	if err != nil && !errors.Is(err, frpc.ErrPayoutUnconfirmed) {
+		m.log.Error("Failed to send admin payout", zap.String("admin", admin), zap.Error(err))
+		return ids.Empty, 0, err
+	}
+	client := frpc.ClientInfoFromContext(ctx)
+	payout.Admin = admin
+	payout.Reason = reason
+	payout.ClientIP = client.IP
+	payout.UserAgent = client.UserAgent
+	if err != nil {
+		// The transfer may have been accepted, so it counts toward the
+		// budget
+		m.log.Error("Admin payout not confirmed", zap.String("admin", admin), zap.Stringer("txID", txID), zap.Error(err))
+		payout.Unconfirmed = true
+		m.recordPayout(ctx, payout, start)
+		return ids.Empty, 0, err
+	}
+	m.recordPayout(ctx, payout, start)
+	m.log.Info("Sent admin payout",
+		zap.Stringer("txID", txID),
+		zap.String("admin", admin),
+		zap.String("destination", address),
+		zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
+		zap.String("reason", reason),
+	)
+	return txID, amount, nil
+}
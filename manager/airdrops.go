@@ -0,0 +1,353 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxAirdropRows is the largest number of lines of an airdrop list
+	MaxAirdropRows = 10_000
+	// DefaultAirdropBatch and MaxAirdropBatch bound the number of rows sent
+	// concurrently by SendAirdrop
+	DefaultAirdropBatch = 20
+	MaxAirdropBatch     = 100
+
+	airdropIDBytes = 8
+
+	// airdropStaleAfter is well above the time a payout takes with its
+	// retries, so rows sending for longer were interrupted
+	airdropStaleAfter = 10 * time.Minute
+)
+
+// CreateAirdrop saves an airdrop named name paying each recipient its amount
+// of asset, ids.Empty for the native asset, on the current chain. A zero
+// amount pays the configured amount. Every address is validated first and
+// all the invalid or repeated lines are reported at once. Nothing is sent
+// until SendAirdrop is called.
+func (m *Manager) CreateAirdrop(ctx context.Context, createdBy, name string, asset ids.ID, recipients []frpc.AirdropRecipient) (*database.Airdrop, []database.AirdropRow, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.CreateAirdrop")
+	defer span.End()
+
+	if len(recipients) == 0 || len(recipients) > MaxAirdropRows {
+		return nil, nil, frpc.ErrInvalidArgument.WithDetail(fmt.Sprintf("airdrop must have between 1 and %d lines", MaxAirdropRows))
+	}
+	id, err := randomString(airdropIDBytes, hex.EncodeToString)
+	if err != nil {
+		return nil, nil, err
+	}
+	airdrop := &database.Airdrop{
+		ID:        id,
+		ChainID:   m.ChainID().String(),
+		Name:      name,
+		CreatedBy: createdBy,
+	}
+	if asset != ids.Empty {
+		airdrop.Asset = asset.String()
+	}
+
+	var (
+		errs  []error
+		lines = make(map[codec.Address]int, len(recipients))
+		rows  = make([]database.AirdropRow, len(recipients))
+		total uint64
+	)
+	defaultAmount := m.Config().Amount
+	now := time.Now().Unix()
+	for i, recipient := range recipients {
+		line := i + 1
+		addr, err := codec.ParseAddressBech32(nconsts.HRP, recipient.Address)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: invalid address %q: %w", line, recipient.Address, err))
+			continue
+		}
+		if previous, ok := lines[addr]; ok {
+			errs = append(errs, fmt.Errorf("line %d: %s is already on line %d", line, recipient.Address, previous))
+			continue
+		}
+		lines[addr] = line
+		amount := recipient.Amount
+		if amount == 0 {
+			amount = defaultAmount
+		}
+		total += amount
+		rows[i] = database.AirdropRow{
+			AirdropID: id,
+			Line:      line,
+			Address:   codec.MustAddressBech32(nconsts.HRP, addr),
+			Amount:    amount,
+			Status:    database.AirdropPending,
+			UpdatedAt: now,
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, frpc.ErrInvalidArgument.Wrap(errors.Join(errs...))
+	}
+	if err := m.db.SaveAirdrop(ctx, airdrop, rows); err != nil {
+		return nil, nil, err
+	}
+	m.log.Info("Created airdrop",
+		zap.String("id", id),
+		zap.String("name", name),
+		zap.Int("rows", len(rows)),
+		zap.String("total", utils.FormatBalance(total, nconsts.Decimals)),
+		zap.String("asset", airdrop.Asset),
+		zap.String("createdBy", createdBy),
+	)
+	return airdrop, rows, nil
+}
+
+// GetAirdrop returns the airdrop id of the current chain with every row
+func (m *Manager) GetAirdrop(ctx context.Context, id string) (*database.Airdrop, []database.AirdropRow, error) {
+	airdrop, err := m.getAirdrop(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := m.db.GetAirdropRows(ctx, id, "", 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return airdrop, rows, nil
+}
+
+// getAirdrop returns the airdrop id if it was created on the current chain
+func (m *Manager) getAirdrop(ctx context.Context, id string) (*database.Airdrop, error) {
+	airdrop, err := m.db.GetAirdrop(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if airdrop.ChainID != m.ChainID().String() {
+		return nil, database.ErrAirdropNotFound
+	}
+	return airdrop, nil
+}
+
+// SendAirdrop sends the next batch of up to batchSize pending rows of the
+// airdrop id concurrently and returns the rows of the batch. retryFailed
+// puts the failed rows back to pending first. Calling it until no row is
+// pending resumes an interrupted airdrop: each row is marked as sending
+// before its payout, so a row is never sent twice. Rows whose transfer may
+// have been accepted, because it was not confirmed or because they were left
+// sending by an interrupted batch, are marked as unconfirmed and only sent
+// again once resolved as failed with ResolveAirdropRow.
+func (m *Manager) SendAirdrop(ctx context.Context, id string, batchSize int, retryFailed bool) ([]database.AirdropRow, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.SendAirdrop")
+	defer span.End()
+
+	if batchSize == 0 {
+		batchSize = DefaultAirdropBatch
+	}
+	if batchSize < 0 || batchSize > MaxAirdropBatch {
+		return nil, frpc.ErrInvalidArgument.WithDetail(fmt.Sprintf("batch size must be between 1 and %d", MaxAirdropBatch))
+	}
+	airdrop, err := m.getAirdrop(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	asset := ids.Empty
+	if airdrop.Asset != "" {
+		if asset, err = ids.FromString(airdrop.Asset); err != nil {
+			return nil, err
+		}
+	}
+	m.l.RLock()
+	err = m.pauseError()
+	m.l.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.failStaleAirdropRows(ctx, id); err != nil {
+		return nil, err
+	}
+	if retryFailed {
+		if err := m.retryAirdropRows(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := m.db.GetAirdropRows(ctx, id, database.AirdropPending, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	batch := make([]database.AirdropRow, 0, len(rows))
+	for _, row := range rows {
+		row.Status, row.UpdatedAt = database.AirdropSending, now
+		claimed, err := m.db.UpdateAirdropRow(ctx, &row, database.AirdropPending)
+		if err != nil {
+			if len(batch) == 0 {
+				return nil, err
+			}
+			// Send the rows already marked as sending rather than leaving
+			// them to go stale
+			m.log.Error("Failed to claim airdrop row", zap.String("id", id), zap.Int("line", row.Line), zap.Error(err))
+			break
+		}
+		if claimed {
+			batch = append(batch, row)
+		}
+	}
+
+	// The batch is sent to the end even if the caller goes away, so no row
+	// is left sending
+	ctx = context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		go func(row *database.AirdropRow) {
+			defer wg.Done()
+			m.sendAirdropRow(ctx, asset, row)
+		}(&batch[i])
+	}
+	wg.Wait()
+	m.log.Info("Sent airdrop batch", zap.String("id", id), zap.Int("rows", len(batch)))
+	return batch, nil
+}
+
+// sendAirdropRow pays row, which must be sending, and records the outcome
+func (m *Manager) sendAirdropRow(ctx context.Context, asset ids.ID, row *database.AirdropRow) {
+	txID, err := m.payAirdropRow(ctx, asset, row)
+	switch {
+	case errors.Is(err, frpc.ErrPayoutUnconfirmed):
+		m.log.Warn("Airdrop row not confirmed", zap.String("id", row.AirdropID), zap.Int("line", row.Line), zap.String("address", row.Address), zap.Stringer("txID", txID), zap.Error(err))
+		row.Status, row.TxID, row.Error = database.AirdropUnconfirmed, txID.String(), err.Error()
+	case err != nil:
+		m.log.Warn("Failed to send airdrop row", zap.String("id", row.AirdropID), zap.Int("line", row.Line), zap.String("address", row.Address), zap.Error(err))
+		row.Status, row.Error = database.AirdropFailed, err.Error()
+	default:
+		row.Status, row.TxID = database.AirdropSent, txID.String()
+	}
+	row.UpdatedAt = time.Now().Unix()
+	if saved, err := m.db.UpdateAirdropRow(ctx, row, database.AirdropSending); err != nil || !saved {
+		m.log.Error("Failed to save airdrop row",
+			zap.String("id", row.AirdropID),
+			zap.Int("line", row.Line),
+			zap.String("status", row.Status),
+			zap.String("txID", row.TxID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (m *Manager) payAirdropRow(ctx context.Context, asset ids.ID, row *database.AirdropRow) (ids.ID, error) {
+	start := time.Now()
+	destination, err := codec.ParseAddressBech32(nconsts.HRP, row.Address)
+	if err != nil {
+		return ids.Empty, err
+	}
+	m.l.RLock()
+	err = m.admit(destination)
+	m.l.RUnlock()
+	if err != nil {
+		return ids.Empty, err
+	}
+	txID, payout, err := m.sendFundsRetry(ctx, destination, asset, row.Amount)
+	if err != nil {
+		return txID, err
+	}
+	m.recordPayout(ctx, payout, start)
+	return txID, nil
+}
+
+// failStaleAirdropRows marks the rows of the airdrop id left sending by an
+// interrupted batch as unconfirmed
+func (m *Manager) failStaleAirdropRows(ctx context.Context, id string) error {
+	sending, err := m.db.GetAirdropRows(ctx, id, database.AirdropSending, 0)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, row := range sending {
+		if now.Sub(time.Unix(row.UpdatedAt, 0)) < airdropStaleAfter {
+			continue
+		}
+		row.Status = database.AirdropUnconfirmed
+		row.Error = "interrupted while sending, the transfer may have been accepted"
+		row.UpdatedAt = now.Unix()
+		if _, err := m.db.UpdateAirdropRow(ctx, &row, database.AirdropSending); err != nil {
+			return err
+		}
+		m.log.Warn("Airdrop row interrupted while sending", zap.String("id", id), zap.Int("line", row.Line), zap.String("address", row.Address))
+	}
+	return nil
+}
+
+// retryAirdropRows puts the failed rows of the airdrop id back to pending
+func (m *Manager) retryAirdropRows(ctx context.Context, id string) error {
+	failed, err := m.db.GetAirdropRows(ctx, id, database.AirdropFailed, 0)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, row := range failed {
+		row.Status, row.Error, row.UpdatedAt = database.AirdropPending, "", now
+		if _, err := m.db.UpdateAirdropRow(ctx, &row, database.AirdropFailed); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		m.log.Info("Retrying failed airdrop rows", zap.String("id", id), zap.Int("rows", len(failed)))
+	}
+	return nil
+}
+
+// ResolveAirdropRow settles the unconfirmed row line of the airdrop id once
+// an admin checked its transfer on chain: as sent, with txID if it was not
+// known, or as failed so retryFailed sends it again
+func (m *Manager) ResolveAirdropRow(ctx context.Context, resolvedBy, id string, line int, sent bool, txID string) (*database.AirdropRow, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.ResolveAirdropRow")
+	defer span.End()
+
+	if _, err := m.getAirdrop(ctx, id); err != nil {
+		return nil, err
+	}
+	unconfirmed, err := m.db.GetAirdropRows(ctx, id, database.AirdropUnconfirmed, 0)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.IndexFunc(unconfirmed, func(row database.AirdropRow) bool { return row.Line == line })
+	if index < 0 {
+		return nil, frpc.ErrInvalidArgument.WithDetail(fmt.Sprintf("line %d of airdrop %s is not unconfirmed", line, id))
+	}
+	row := unconfirmed[index]
+	if sent {
+		row.Status, row.Error = database.AirdropSent, ""
+		if txID != "" {
+			row.TxID = txID
+		}
+	} else {
+		row.Status, row.Error = database.AirdropFailed, "resolved as not sent by "+resolvedBy
+	}
+	row.UpdatedAt = time.Now().Unix()
+	resolved, err := m.db.UpdateAirdropRow(ctx, &row, database.AirdropUnconfirmed)
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return nil, frpc.ErrInvalidArgument.WithDetail(fmt.Sprintf("line %d of airdrop %s is not unconfirmed", line, id))
+	}
+	m.log.Info("Resolved airdrop row",
+		zap.String("id", id),
+		zap.Int("line", line),
+		zap.String("status", row.Status),
+		zap.String("txID", row.TxID),
+		zap.String("resolvedBy", resolvedBy),
+	)
+	return &row, nil
+}
@@ -0,0 +1,221 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so leaked keys are easy to spot
+	APIKeyPrefix = "nfk_"
+
+	apiKeyBytes   = 32
+	apiKeyIDBytes = 8
+
+	day = 24 * time.Hour
+)
+
+// CreateAPIKey issues a key named name with the given daily quotas, 0 for no
+// limit. assets are asset IDs or NAI for the native asset, only the native
+// asset if empty, and destinations are the addresses the key may pay, any if
+// empty. It returns the key, which is only known to the caller from then on.
+func (m *Manager) CreateAPIKey(ctx context.Context, createdBy, name string, dailyAmount uint64, dailyRequests uint32, assets, destinations []string) (string, *database.APIKey, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.CreateAPIKey")
+	defer span.End()
+
+	if name == "" {
+		return "", nil, frpc.ErrInvalidArgument.WithDetail("API key name must not be empty")
+	}
+	key := &database.APIKey{
+		Name:          name,
+		DailyAmount:   dailyAmount,
+		DailyRequests: dailyRequests,
+		CreatedBy:     createdBy,
+	}
+	for _, asset := range assets {
+		id, err := parseAPIKeyAsset(asset)
+		if err != nil {
+			return "", nil, err
+		}
+		key.AllowedAssets = append(key.AllowedAssets, formatAPIKeyAsset(id))
+	}
+	for _, destination := range destinations {
+		if _, err := codec.ParseAddressBech32(nconsts.HRP, destination); err != nil {
+			return "", nil, frpc.ErrInvalidArgument.Wrap(fmt.Errorf("invalid destination %q: %w", destination, err))
+		}
+		key.AllowedDestinations = append(key.AllowedDestinations, destination)
+	}
+
+	var err error
+	if key.ID, err = randomString(apiKeyIDBytes, hex.EncodeToString); err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(apiKeyBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret = APIKeyPrefix + secret
+	key.KeyHash = hashAPIKey(secret)
+	if err := m.db.SaveAPIKey(ctx, key); err != nil {
+		return "", nil, err
+	}
+	m.log.Info("Created API key", zap.String("id", key.ID), zap.String("name", name), zap.String("createdBy", createdBy))
+	return secret, key, nil
+}
+
+func (m *Manager) GetAPIKeys(ctx context.Context) ([]database.APIKey, error) {
+	return m.db.GetAPIKeys(ctx)
+}
+
+// RevokeAPIKey returns false if id is unknown or already revoked
+func (m *Manager) RevokeAPIKey(ctx context.Context, id string) (bool, error) {
+	revoked, err := m.db.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		m.log.Info("Revoked API key", zap.String("id", id))
+	}
+	return revoked, nil
+}
+
+// GetAPIKeyUsage returns the daily usage of the key id, or of every key if
+// empty, over the last days days including today
+func (m *Manager) GetAPIKeyUsage(ctx context.Context, id string, days int) ([]database.APIKeyUsage, error) {
+	if days <= 0 {
+		days = 1
+	}
+	return m.db.GetAPIKeyUsage(ctx, id, today()-int64(days)+1)
+}
+
+// ClaimWithAPIKey pays amount of asset, ids.Empty for the native asset, to
+// destination without a challenge if the key allows it. A zero amount pays
+// the configured amount, which is also the most a single native claim can
+// pay. The request is counted against the quotas of the key before paying
+// out and taken back if the payout fails before its transaction is sent.
+func (m *Manager) ClaimWithAPIKey(ctx context.Context, secret string, destination codec.Address, asset ids.ID, amount uint64) (ids.ID, uint64, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.ClaimWithAPIKey")
+	defer span.End()
+
+	start := time.Now()
+	key, err := m.db.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, database.ErrAPIKeyNotFound) || (err == nil && key.RevokedAt != 0) {
+		return ids.Empty, 0, frpc.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	address := codec.MustAddressBech32(nconsts.HRP, destination)
+	m.l.RLock()
+	err = m.admit(destination)
+	m.l.RUnlock()
+	if err != nil {
+		m.log.Warn("Rejecting API key claim", zap.String("key", key.ID), zap.String("address", address), zap.Error(err))
+		return ids.Empty, 0, err
+	}
+	if !apiKeyAllowsAsset(key, asset) {
+		return ids.Empty, 0, frpc.ErrAssetNotAllowed.WithDetail(formatAPIKeyAsset(asset))
+	}
+	if len(key.AllowedDestinations) > 0 && !slices.Contains(key.AllowedDestinations, address) {
+		return ids.Empty, 0, frpc.ErrDestinationNotAllowed.WithDetail(address)
+	}
+	// Native claims are capped so a key without a daily amount cannot drain
+	// the wallets at once. The configured amount is in native units, other
+	// assets are only bounded by the daily amount of the key
+	if limit := m.Config().Amount; amount == 0 {
+		amount = limit
+	} else if asset == ids.Empty && amount > limit {
+		return ids.Empty, 0, frpc.ErrAmountNotAllowed.WithDetail(fmt.Sprintf("%d > %d", amount, limit))
+	}
+
+	now := today()
+	used, err := m.db.UseAPIKey(ctx, key, now, amount)
+	if err != nil {
+		return ids.Empty, 0, err
+	}
+	if !used {
+		m.log.Warn("API key quota exceeded", zap.String("key", key.ID), zap.Uint64("amount", amount))
+		tomorrow := time.Unix((now+1)*int64(day/time.Second), 0)
+		return ids.Empty, 0, frpc.ErrQuotaExceeded.WithRetryAfter(time.Until(tomorrow))
+	}
+	txID, payout, err := m.sendFundsRetry(ctx, destination, asset, amount)
+	if err != nil {
+		m.log.Error("Failed to send API key funds", zap.String("key", key.ID), zap.Error(err))
+		// A use whose transfer may have been accepted stays counted
+		if !errors.Is(err, frpc.ErrPayoutUnconfirmed) {
+			if err := m.db.ReleaseAPIKeyUse(ctx, key.ID, now, amount); err != nil {
+				m.log.Error("Failed to release API key use", zap.String("key", key.ID), zap.Error(err))
+			}
+		}
+		return ids.Empty, 0, err
+	}
+	client := frpc.ClientInfoFromContext(ctx)
+	payout.APIKeyID = key.ID
+	payout.ClientIP = client.IP
+	payout.UserAgent = client.UserAgent
+	m.recordPayout(ctx, payout, start)
+	m.log.Info("Fauceted funds with API key",
+		zap.Stringer("txID", txID),
+		zap.String("key", key.ID),
+		zap.String("destination", address),
+		zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
+		zap.String("asset", formatAPIKeyAsset(asset)),
+	)
+	return txID, amount, nil
+}
+
+// hashAPIKey returns the hex SHA-256 of key. Keys are random enough that a
+// plain hash cannot be reversed.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// today returns the current UTC day in days since the unix epoch
+func today() int64 {
+	return time.Now().Unix() / int64(day/time.Second)
+}
+
+// parseAPIKeyAsset accepts NAI for the native asset
+func parseAPIKeyAsset(asset string) (ids.ID, error) {
+	if strings.EqualFold(asset, nconsts.Symbol) {
+		return ids.Empty, nil
+	}
+	id, err := ids.FromString(asset)
+	if err != nil {
+		return ids.Empty, frpc.ErrInvalidArgument.Wrap(fmt.Errorf("invalid asset %q: %w", asset, err))
+	}
+	return id, nil
+}
+
+func formatAPIKeyAsset(asset ids.ID) string {
+	if asset == ids.Empty {
+		return nconsts.Symbol
+	}
+	return asset.String()
+}
+
+func apiKeyAllowsAsset(key *database.APIKey, asset ids.ID) bool {
+	if len(key.AllowedAssets) == 0 {
+		return asset == ids.Empty
+	}
+	return slices.Contains(key.AllowedAssets, formatAPIKeyAsset(asset))
+}
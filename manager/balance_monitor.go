@@ -0,0 +1,144 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/alert"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+// lowBalancePauseMessage marks pauses made by the balance monitor, so only
+// those are lifted automatically once the balance recovers
+const lowBalancePauseMessage = "faucet balance is critically low"
+
+// monitorBalance checks the balance every BalanceCheckInterval until ctx is
+// done
+func (m *Manager) monitorBalance(ctx context.Context) {
+	for {
+		m.checkBalanceLevel(ctx)
+		// The interval is read every time as a reload may change it
+		t := time.NewTimer(time.Duration(m.Config().BalanceCheckInterval) * time.Second)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// checkBalanceLevel compares the balance with the alert thresholds and
+// notifies the webhooks whenever the level changes
+func (m *Manager) checkBalanceLevel(ctx context.Context) {
+	ctx, span := m.tracer.Start(ctx, "Manager.checkBalanceLevel")
+	defer span.End()
+
+	bal, err := m.GetBalance(ctx)
+	if err != nil {
+		m.log.Warn("Failed to check balance", zap.Error(err))
+		return
+	}
+	if m.refill(ctx, bal) {
+		if bal, err = m.GetBalance(ctx); err != nil {
+			m.log.Warn("Failed to check balance", zap.Error(err))
+			return
+		}
+	}
+	m.rebalance(ctx)
+	config := m.Config()
+	depletion := m.estimateDepletion(ctx, bal)
+	m.metrics.depletion.Set(depletion.Seconds())
+
+	level, threshold := alert.LevelOK, config.BalanceWarningThreshold
+	switch {
+	case bal < config.BalanceCriticalThreshold:
+		level, threshold = alert.LevelCritical, config.BalanceCriticalThreshold
+	case bal < config.BalanceWarningThreshold:
+		level = alert.LevelWarning
+	}
+	if level == m.balanceLevel {
+		return
+	}
+
+	paused := m.applyBalancePause(ctx, level)
+	a := &alert.Alert{
+		Level:     level,
+		Address:   m.wallets.primary().bech32,
+		Balance:   bal,
+		Threshold: threshold,
+		Depletion: int64(depletion.Seconds()),
+		Paused:    paused,
+		Timestamp: time.Now().Unix(),
+	}
+	if bal < config.BalanceWarningThreshold {
+		a.RequestedRefill = config.BalanceWarningThreshold - bal
+	}
+	switch level {
+	case alert.LevelCritical:
+		a.Message = "faucet balance is below the critical threshold"
+	case alert.LevelWarning:
+		a.Message = "faucet balance is below the warning threshold"
+	default:
+		a.Message = "faucet balance recovered"
+	}
+	m.log.Warn("Balance level changed",
+		zap.String("from", string(m.balanceLevel)),
+		zap.String("to", string(level)),
+		zap.String("balance", utils.FormatBalance(bal, nconsts.Decimals)),
+		zap.Duration("depletion", depletion),
+	)
+	m.balanceLevel = level
+
+	m.l.RLock()
+	notifier := m.notifier
+	m.l.RUnlock()
+	if err := notifier.Notify(ctx, a); err != nil {
+		m.log.Error("Failed to deliver balance alert", zap.Error(err))
+	}
+}
+
+// applyBalancePause pauses payouts at the critical level, if configured, and
+// lifts a pause made by the monitor once the balance recovers. It returns
+// whether payouts are paused afterwards.
+func (m *Manager) applyBalancePause(ctx context.Context, level alert.Level) bool {
+	paused, message, _, _ := m.GetPauseState(ctx)
+	switch {
+	case level == alert.LevelCritical && m.Config().PauseOnCriticalBalance && !paused:
+		if err := m.Pause(ctx, lowBalancePauseMessage, 0); err != nil {
+			return false
+		}
+		return true
+	case level != alert.LevelCritical && paused && message == lowBalancePauseMessage:
+		if err := m.Resume(ctx); err != nil {
+			return true
+		}
+		return false
+	}
+	return paused
+}
+
+// estimateDepletion extrapolates the payouts of the last 24 hours to
+// estimate when bal runs out. It returns 0 if nothing was paid out.
+func (m *Manager) estimateDepletion(ctx context.Context, bal uint64) time.Duration {
+	stats, err := m.db.GetStats(ctx, m.ChainID().String())
+	if err != nil {
+		m.log.Warn("Failed to fetch payout stats", zap.Error(err))
+		return 0
+	}
+	if stats.Last24hAmount == 0 {
+		return 0
+	}
+	perSecond := float64(stats.Last24hAmount) / (24 * time.Hour).Seconds()
+	seconds := float64(bal) / perSecond
+	if seconds >= float64(math.MaxInt64/int64(time.Second)) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/hypersdk/utils"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+// Readiness components
+const (
+	ComponentDatabase  = "database"
+	ComponentJSONRPC   = "jsonrpc"
+	ComponentWebSocket = "websocket"
+	ComponentBalance   = "balance"
+	ComponentSaltTimer = "saltTimer"
+)
+
+// ComponentHealth is the result of checking a single dependency
+type ComponentHealth struct {
+	Healthy  bool   `json:"healthy"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Readiness is the per-component breakdown returned by CheckReadiness
+type Readiness struct {
+	Ready      bool                       `json:"ready"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// CheckReadiness checks every dependency the faucet needs to serve payouts.
+// The checks run concurrently and each is bounded by a timeout.
+func (m *Manager) CheckReadiness(ctx context.Context) *Readiness {
+	ctx, span := m.tracer.Start(ctx, "Manager.CheckReadiness")
+	defer span.End()
+
+	checks := map[string]func(context.Context) (string, error){
+		ComponentDatabase:  m.checkDatabase,
+		ComponentJSONRPC:   m.checkJSONRPC,
+		ComponentWebSocket: m.checkWebSocket,
+		ComponentBalance:   m.checkBalance,
+		ComponentSaltTimer: m.checkSaltTimer,
+	}
+
+	var (
+		l      sync.Mutex
+		wg     sync.WaitGroup
+		report = &Readiness{Ready: true, Components: make(map[string]ComponentHealth, len(checks))}
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) (string, error)) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			msg, err := check(ctx)
+			result := ComponentHealth{Healthy: err == nil, Message: msg, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Message = err.Error()
+			}
+
+			l.Lock()
+			defer l.Unlock()
+			report.Components[name] = result
+			report.Ready = report.Ready && result.Healthy
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (m *Manager) checkDatabase(ctx context.Context) (string, error) {
+	return "", m.db.Ping(ctx)
+}
+
+func (m *Manager) checkJSONRPC(ctx context.Context) (string, error) {
+	m.l.RLock()
+	cli := m.cli
+	m.l.RUnlock()
+
+	ok, err := cli.Ping(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("ping not acknowledged")
+	}
+	return "", nil
+}
+
+func (m *Manager) checkWebSocket(_ context.Context) (string, error) {
+	connected := 0
+	for _, w := range m.wallets.wallets {
+		if w.connected() {
+			connected++
+		}
+	}
+	msg := fmt.Sprintf("%d/%d wallets connected", connected, len(m.wallets.wallets))
+	if connected < len(m.wallets.wallets) {
+		return "", errors.New(msg)
+	}
+	return msg, nil
+}
+
+func (m *Manager) checkBalance(ctx context.Context) (string, error) {
+	bal, err := m.GetBalance(ctx)
+	if err != nil {
+		return "", err
+	}
+	formatted := utils.FormatBalance(bal, nconsts.Decimals)
+	if minBalance := m.Config().MinBalance; bal < minBalance {
+		return "", fmt.Errorf("balance %s %s below minimum %s", formatted, nconsts.Symbol, utils.FormatBalance(minBalance, nconsts.Decimals))
+	}
+	return fmt.Sprintf("%s %s", formatted, nconsts.Symbol), nil
+}
+
+// checkSaltTimer reports the timer as stalled once the salt is older than
+// twice the target duration, leaving room for a rotation to be skipped
+func (m *Manager) checkSaltTimer(_ context.Context) (string, error) {
+	m.l.RLock()
+	age := time.Since(time.Unix(m.lastRotation, 0))
+	maxAge := 2 * time.Duration(m.config.TargetDurationPerSalt) * time.Second
+	m.l.RUnlock()
+
+	if age > maxAge {
+		return "", fmt.Errorf("salt not rotated for %s", age.Round(time.Second))
+	}
+	return fmt.Sprintf("salt age %s", age.Round(time.Second)), nil
+}
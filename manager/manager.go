@@ -6,54 +6,85 @@ package manager
 import (
 	"bytes"
 	"context"
-	"database/sql"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
-	"strings"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/trace"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/set"
 	"github.com/ava-labs/avalanchego/utils/timer"
 	"github.com/ava-labs/hypersdk/chain"
 	"github.com/ava-labs/hypersdk/codec"
-	"github.com/ava-labs/hypersdk/pubsub"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/rpc"
 	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/alert"
 	fconfig "github.com/nuklai/nuklai-faucet/config"
 	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	"github.com/nuklai/nuklai-faucet/signer"
 	"github.com/nuklai/nuklaivm/actions"
-	"github.com/nuklai/nuklaivm/auth"
 	"github.com/nuklai/nuklaivm/challenge"
 	nconsts "github.com/nuklai/nuklaivm/consts"
 	nrpc "github.com/nuklai/nuklaivm/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
+// deniedRefreshInterval is how often the deny list is reloaded from the
+// database
+const deniedRefreshInterval = time.Minute
+
 type Manager struct {
 	log    logging.Logger
 	config *fconfig.Config
 
-	cli  *rpc.JSONRPCClient
-	scli *rpc.WebSocketClient
-	ncli *nrpc.JSONRPCClient
+	cli     *rpc.JSONRPCClient
+	ncli    *nrpc.JSONRPCClient
+	chainID ids.ID
 
-	factory *auth.ED25519Factory
+	wallets  *walletPool
+	treasury *wallet // nil when refills are disabled
 
 	l            sync.RWMutex
 	t            *timer.Timer
 	lastRotation int64
 	salt         []byte
+	nonceKey     []byte // derives the nonce of each salt
 	difficulty   uint16
 	solutions    set.Set[ids.ID]
 	cancelFunc   context.CancelFunc
+	pause        database.PauseState
+	denied       set.Set[codec.Address]
+
+	// adminL serializes admin payouts so they stay within the budget
+	adminL sync.Mutex
 
-	db *database.DB
+	// balanceLevel is only accessed by the balance monitor
+	balanceLevel alert.Level
+	notifier     *alert.Notifier
+
+	db      database.Store
+	metrics *metrics
+	tracer  trace.Tracer
 }
 
-func New(logger logging.Logger, config *fconfig.Config, db *sql.DB) (*Manager, error) {
+// New creates a manager paying out from every key held by s
+func New(logger logging.Logger, config *fconfig.Config, s signer.Signer, db database.Store, registry prometheus.Registerer, tracer trace.Tracer) (*Manager, error) {
+	metrics, err := newMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	cli := rpc.NewJSONRPCClient(config.NuklaiRPC)
 	networkID, _, chainID, err := cli.Network(ctx)
@@ -62,39 +93,75 @@ func New(logger logging.Logger, config *fconfig.Config, db *sql.DB) (*Manager, e
 		return nil, err
 	}
 
-	scli, err := rpc.NewWebSocketClient(
-		config.NuklaiRPC,
-		rpc.DefaultHandshakeTimeout,
-		pubsub.MaxPendingMessages,
-		pubsub.MaxReadMessageSize,
-	)
+	pks, err := s.PublicKeys(ctx)
 	if err != nil {
-		return nil, err
+		cancel()
+		return nil, fmt.Errorf("cannot list signer keys: %w", err)
+	}
+	if len(pks) == 0 {
+		cancel()
+		return nil, errors.New("signer holds no keys")
+	}
+	var wallets []*wallet
+	for _, pk := range pks {
+		w, err := newWallet(s, pk, config.NuklaiRPC)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		wallets = append(wallets, w)
 	}
 
 	ncli := nrpc.NewJSONRPCClient(config.NuklaiRPC, networkID, chainID)
 
-	dbInstance, err := database.NewDB(db)
-	if err != nil {
-		cancel()
-		return nil, err
+	m := &Manager{log: logger, config: config, cli: cli, ncli: ncli, chainID: chainID, wallets: newWalletPool(wallets, metrics), cancelFunc: cancel, db: db, metrics: metrics, tracer: tracer}
+	m.balanceLevel = alert.LevelOK
+	m.notifier = alert.NewNotifier(config.BalanceWebhooks)
+	if config.HasTreasury() {
+		treasury := config.TreasuryPrivateKey()
+		m.treasury, err = newWallet(signer.NewLocal([]ed25519.PrivateKey{treasury}, signer.Policy{}), treasury.PublicKey(), config.NuklaiRPC)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
 	}
-	m := &Manager{log: logger, config: config, cli: cli, scli: scli, ncli: ncli, factory: auth.NewED25519Factory(config.PrivateKey()), cancelFunc: cancel, db: dbInstance}
 	m.lastRotation = time.Now().Unix()
+	m.metrics.lastRotation.Store(m.lastRotation)
 	m.difficulty = m.config.StartDifficulty
+	m.metrics.difficulty.Set(float64(m.difficulty))
 	m.solutions = set.NewSet[ids.ID](m.config.SolutionsPerSalt)
 	m.salt, err = challenge.New()
 	if err != nil {
+		cancel()
+		return nil, err
+	}
+	m.nonceKey = make([]byte, sha256.Size)
+	if _, err := rand.Read(m.nonceKey); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := m.loadPauseState(ctx); err != nil {
+		cancel()
 		return nil, err
 	}
-	bal, err := ncli.Balance(ctx, m.config.AddressBech32(), nconsts.Symbol)
+	if err := m.loadDeniedAddresses(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	bal, err := m.refreshBalances(ctx, ncli)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	m.log.Info("faucet initialized",
-		zap.String("address", m.config.AddressBech32()),
+		zap.String("network", config.Network),
+		zap.Stringer("chain ID", chainID),
+		zap.String("address", m.wallets.primary().bech32),
+		zap.Int("wallets", len(wallets)),
 		zap.Uint16("difficulty", m.difficulty),
 		zap.String("balance", utils.FormatBalance(bal, nconsts.Decimals)),
+		zap.Bool("paused", m.pause.Paused),
+		zap.Bool("treasury", m.treasury != nil),
 	)
 	m.t = timer.NewTimer(m.updateDifficulty)
 	return m, nil
@@ -102,53 +169,88 @@ func New(logger logging.Logger, config *fconfig.Config, db *sql.DB) (*Manager, e
 
 func (m *Manager) Run(ctx context.Context) error {
 	m.log.Info("Manager run started")
-	m.t.SetTimeoutIn(time.Duration(m.config.TargetDurationPerSalt) * time.Second)
+	m.t.SetTimeoutIn(time.Duration(m.Config().TargetDurationPerSalt) * time.Second)
 	go m.t.Dispatch()
+	go m.monitorBalance(ctx)
+	go m.pruneSolutions(ctx)
+	go m.refreshDeniedAddresses(ctx)
 	<-ctx.Done()
 	m.t.Stop()
-	m.db.Close()
 	m.log.Info("Manager run completed", zap.Error(ctx.Err()))
 	return ctx.Err()
 }
 
-func (m *Manager) WebSocketreconnect() error {
-	if m.scli != nil {
-		m.log.Info("Closing old WS connection.")
-        m.scli.Close()
-    }
-    scli, err := rpc.NewWebSocketClient(
-        m.config.NuklaiRPC,
-        rpc.DefaultHandshakeTimeout,
-        pubsub.MaxPendingMessages,
-        pubsub.MaxReadMessageSize,
-    )
-    if err != nil {
-        return err
-    }
-    m.scli = scli
-	m.log.Info("WS connection re-established.")
-    return nil
-}
-
-func (m *Manager) sendFundsRetry(ctx context.Context, destination codec.Address, amount uint64) (ids.ID, uint64, error) {
-    var lastErr error
-    for retries := 0; retries < 3; retries++ {
-        txID, maxFee, err := m.sendFunds(ctx, destination, amount)
-        if err == nil {
-            return txID, maxFee, nil
-        }
-        
-        if strings.Contains(err.Error(), "closed") {
-            if reconnErr := m.WebSocketreconnect(); reconnErr != nil {
-                m.log.Error("Error reconnecting to WS", zap.Error(reconnErr))
-                continue
-            }
-        }
-        
-        lastErr = err
-        time.Sleep(time.Second * time.Duration(retries+1))
-    }
-    return ids.Empty, 0, fmt.Errorf("failed after retries: %w", lastErr)
+// WebSocketreconnect replaces the WebSocket connection of w
+func (m *Manager) WebSocketreconnect(w *wallet) error {
+	m.l.RLock()
+	uri := m.config.NuklaiRPC
+	m.l.RUnlock()
+
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	m.log.Info("Closing old WS connection.", zap.String("address", w.bech32))
+	if err := w.connect(uri); err != nil {
+		m.metrics.websocketReconnects.WithLabelValues("failure").Inc()
+		return err
+	}
+	m.metrics.websocketReconnects.WithLabelValues("success").Inc()
+	m.log.Info("WS connection re-established.", zap.String("address", w.bech32))
+	return nil
+}
+
+// sendFundsRetry sends amount of asset, ids.Empty for the native asset, to
+// destination, retrying failed attempts. The returned payout is not saved,
+// callers complete and record it with recordPayout. An attempt whose
+// transaction was registered is never retried: it fails with
+// ErrPayoutUnconfirmed along with the transaction and the payout, and
+// callers must not release what the payout consumed, since the transfer may
+// have been accepted.
+func (m *Manager) sendFundsRetry(ctx context.Context, destination codec.Address, asset ids.ID, amount uint64) (txID ids.ID, payout *database.Transaction, err error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.sendFundsRetry")
+	defer span.End()
+	defer func(start time.Time) {
+		var maxFee uint64
+		if payout != nil {
+			maxFee = payout.Fee
+		}
+		m.metrics.observePayout(time.Since(start), maxFee, err)
+	}(time.Now())
+
+	var lastErr error
+	for retries := 0; retries < 3; retries++ {
+		span.AddEvent("attempt", oteltrace.WithAttributes(attribute.Int("retry", retries)))
+		txID, payout, err := m.sendFunds(ctx, destination, asset, amount)
+		if err == nil {
+			return txID, payout, nil
+		}
+		lastErr = err
+		if errors.Is(err, frpc.ErrNetworkFeeTooHigh) || errors.Is(err, frpc.ErrInsufficientFunds) || errors.Is(err, signer.ErrPolicyViolation) {
+			// Retrying right away will not change the outcome
+			return ids.Empty, nil, err
+		}
+		if errors.Is(err, frpc.ErrPayoutUnconfirmed) {
+			// The transaction may still be accepted, sending it again could
+			// pay twice
+			span.RecordError(err)
+			return txID, payout, err
+		}
+
+		time.Sleep(time.Second * time.Duration(retries+1))
+	}
+	span.RecordError(lastErr)
+	return ids.Empty, nil, frpc.ErrPayoutFailed.Wrap(lastErr)
+}
+
+// recordPayout saves payout along with the time spent processing the request
+// since start
+func (m *Manager) recordPayout(ctx context.Context, payout *database.Transaction, start time.Time) {
+	payout.ProcessingTime = time.Since(start).Milliseconds()
+	if err := m.db.SaveTransaction(ctx, payout); err != nil {
+		m.log.Error("Failed to save transaction", zap.String("txID", payout.TxID), zap.Error(err))
+		return
+	}
+	m.log.Info("Transaction saved", zap.String("txID", payout.TxID), zap.String("destination", payout.Destination), zap.Uint64("amount", payout.Amount))
 }
 
 func (m *Manager) updateDifficulty() {
@@ -162,9 +264,11 @@ func (m *Manager) updateDifficulty() {
 
 	if m.difficulty > m.config.StartDifficulty && m.solutions.Len() == 0 {
 		m.difficulty--
+		m.metrics.difficulty.Set(float64(m.difficulty))
 		m.log.Info("Decreasing faucet difficulty", zap.Uint16("new difficulty", m.difficulty))
 	}
 	m.lastRotation = time.Now().Unix()
+	m.metrics.lastRotation.Store(m.lastRotation)
 	salt, err := challenge.New()
 	if err != nil {
 		panic(err)
@@ -175,7 +279,13 @@ func (m *Manager) updateDifficulty() {
 }
 
 func (m *Manager) GetFaucetAddress(_ context.Context) (codec.Address, error) {
-	return m.config.Address(), nil
+	return m.wallets.primary().address, nil
+}
+
+// GetFaucetAddresses returns the address of every faucet wallet, starting
+// with the primary one
+func (m *Manager) GetFaucetAddresses(_ context.Context) ([]codec.Address, error) {
+	return m.wallets.addresses(), nil
 }
 
 func (m *Manager) GetChallenge(_ context.Context) ([]byte, uint16, error) {
@@ -185,46 +295,158 @@ func (m *Manager) GetChallenge(_ context.Context) ([]byte, uint16, error) {
 	return m.salt, m.difficulty, nil
 }
 
-func (m *Manager) sendFunds(ctx context.Context, destination codec.Address, amount uint64) (ids.ID, uint64, error) {
-	parser, err := m.ncli.Parser(ctx)
+// ChallengeNonce returns the nonce ownership proofs of solutions to salt
+// sign. It is derived from the salt with a key only this process knows, so
+// it changes along with the salt and cannot be predicted by clients.
+func (m *Manager) ChallengeNonce(salt []byte) []byte {
+	mac := hmac.New(sha256.New, m.nonceKey)
+	mac.Write(salt)
+	return mac.Sum(nil)
+}
+
+func (m *Manager) sendFunds(ctx context.Context, destination codec.Address, asset ids.ID, amount uint64) (ids.ID, *database.Transaction, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.sendFunds",
+		oteltrace.WithAttributes(
+			attribute.String("destination", codec.MustAddressBech32(nconsts.HRP, destination)),
+			attribute.Stringer("asset", asset),
+			attribute.Int64("amount", int64(amount)),
+		),
+	)
+	defer span.End()
+
+	// Wallet balances are tracked in the native asset only, transfer checks
+	// the balance of other assets
+	native := amount
+	if asset != ids.Empty {
+		native = 0
+	}
+	w, err := m.wallets.acquire(ctx, native)
+	if err != nil {
+		return ids.Empty, nil, err
+	}
+	defer m.wallets.release(w)
+	span.SetAttributes(attribute.String("wallet", w.bech32))
+
+	m.l.RLock()
+	endpoint, chainID := m.config.NuklaiRPC, m.chainID
+	m.l.RUnlock()
+
+	txID, maxFee, transferErr := m.transfer(ctx, w, destination, asset, amount)
+	if transferErr != nil {
+		if strings.Contains(transferErr.Error(), "closed") {
+			if reconnErr := m.WebSocketreconnect(w); reconnErr != nil {
+				m.log.Error("Error reconnecting to WS", zap.Error(reconnErr))
+			}
+		}
+		if !errors.Is(transferErr, frpc.ErrPayoutUnconfirmed) {
+			return ids.Empty, nil, transferErr
+		}
+	}
+	span.SetAttributes(attribute.Stringer("txID", txID))
+
+	destinationAddr, err := codec.AddressBech32(nconsts.HRP, destination)
+	if err != nil {
+		m.log.Error("Failed to convert address to bech32", zap.Error(err))
+		return ids.Empty, nil, err
+	}
+	payout := &database.Transaction{
+		TxID:        txID.String(),
+		ChainID:     chainID.String(),
+		Destination: destinationAddr,
+		Amount:      amount,
+		Fee:         maxFee,
+		RPCEndpoint: endpoint,
+	}
+	if asset != ids.Empty {
+		payout.Asset = asset.String()
+	}
+	return txID, payout, transferErr
+}
+
+// transfer sends amount of asset from w to destination and waits for the
+// transaction to be accepted. Failures after the transaction was registered
+// are ErrPayoutUnconfirmed, returned along with the transaction ID and the
+// max fee.
+func (m *Manager) transfer(ctx context.Context, w *wallet, destination codec.Address, asset ids.ID, amount uint64) (ids.ID, uint64, error) {
+	m.l.RLock()
+	cli, ncli := m.cli, m.ncli
+	m.l.RUnlock()
+
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	parserCtx, parserSpan := m.tracer.Start(ctx, "chain.Parser")
+	parser, err := ncli.Parser(parserCtx)
+	parserSpan.End()
 	if err != nil {
 		m.log.Error("Failed to create parser", zap.Error(err))
 		return ids.Empty, 0, err
 	}
-	_, tx, maxFee, err := m.cli.GenerateTransaction(ctx, parser, []chain.Action{&actions.Transfer{
+	genCtx, genSpan := m.tracer.Start(ctx, "chain.GenerateTransaction")
+	_, tx, maxFee, err := cli.GenerateTransaction(genCtx, parser, []chain.Action{&actions.Transfer{
 		To:    destination,
-		Asset: ids.Empty,
+		Asset: asset,
 		Value: amount,
-	}}, m.factory)
+	}}, w.factory)
+	genSpan.End()
 	if err != nil {
 		m.log.Error("Failed to generate transaction", zap.Error(err))
 		return ids.Empty, 0, err
 	}
-	if amount < maxFee {
+	m.wallets.setFee(maxFee)
+	// The fee is paid in the native asset, the amount of other assets
+	// cannot be compared with it
+	native := amount
+	if asset != ids.Empty {
+		native = 0
+	} else if amount < maxFee {
 		m.log.Warn("Abandoning airdrop because network fee is greater than amount", zap.String("maxFee", utils.FormatBalance(maxFee, nconsts.Decimals)))
-		return ids.Empty, 0, errors.New("network fee too high")
+		return ids.Empty, 0, frpc.ErrNetworkFeeTooHigh
 	}
-	bal, err := m.ncli.Balance(ctx, m.config.AddressBech32(), nconsts.Symbol)
+	balCtx, balSpan := m.tracer.Start(ctx, "chain.Balance")
+	bal, err := ncli.Balance(balCtx, w.bech32, nconsts.Symbol)
+	balSpan.End()
 	if err != nil {
 		m.log.Error("Failed to fetch balance", zap.Error(err))
 		return ids.Empty, 0, err
 	}
-	if bal < maxFee+amount {
-		m.log.Warn("Faucet has insufficient funds", zap.String("balance", utils.FormatBalance(bal, nconsts.Decimals)))
-		return ids.Empty, 0, errors.New("insufficient balance")
+	m.wallets.setBalance(w, bal)
+	if bal < maxFee+native {
+		m.log.Warn("Account has insufficient funds", zap.String("address", w.bech32), zap.String("balance", utils.FormatBalance(bal, nconsts.Decimals)))
+		return ids.Empty, 0, frpc.ErrInsufficientFunds
+	}
+	if asset != ids.Empty {
+		assetCtx, assetSpan := m.tracer.Start(ctx, "chain.Balance", oteltrace.WithAttributes(attribute.Stringer("asset", asset)))
+		assetBal, err := ncli.Balance(assetCtx, w.bech32, asset.String())
+		assetSpan.End()
+		if err != nil {
+			m.log.Error("Failed to fetch asset balance", zap.Stringer("asset", asset), zap.Error(err))
+			return ids.Empty, 0, err
+		}
+		if assetBal < amount {
+			m.log.Warn("Account has insufficient asset funds", zap.String("address", w.bech32), zap.Stringer("asset", asset), zap.Uint64("balance", assetBal))
+			return ids.Empty, 0, frpc.ErrInsufficientFunds
+		}
 	}
 
-	if err = m.scli.RegisterTx(tx); err != nil {
+	scli := w.scli.Load()
+	_, registerSpan := m.tracer.Start(ctx, "chain.RegisterTx")
+	err = scli.RegisterTx(tx)
+	registerSpan.End()
+	if err != nil {
 		m.log.Error("Failed to register transaction", zap.Error(err))
 		return ids.Empty, 0, err
 	}
+	listenCtx, listenSpan := m.tracer.Start(ctx, "chain.ListenTx")
+	defer listenSpan.End()
 	for {
-		txID, dErr, _, err := m.scli.ListenTx(ctx)
+		txID, dErr, _, err := scli.ListenTx(listenCtx)
 		if dErr != nil {
-			return ids.Empty, 0, dErr
+			err = dErr
 		}
 		if err != nil {
-			return ids.Empty, 0, err
+			m.log.Error("Failed to confirm transaction", zap.Stringer("txID", tx.ID()), zap.String("address", w.bech32), zap.Error(err))
+			return tx.ID(), maxFee, frpc.ErrPayoutUnconfirmed.Wrap(fmt.Errorf("transaction %s: %w", tx.ID(), err))
 		}
 		if txID == tx.ID() {
 			break
@@ -232,53 +454,73 @@ func (m *Manager) sendFunds(ctx context.Context, destination codec.Address, amou
 		// TODO: don't drop these results (may be needed by a different connection)
 		m.log.Warn("skipping unexpected transaction", zap.String("txID", tx.ID().String()))
 	}
+	// The fee actually charged may be lower, the balance is refreshed by the
+	// balance monitor
+	m.wallets.setBalance(w, bal-native-maxFee)
+	return tx.ID(), maxFee, nil
+}
 
-	destinationAddr, err := codec.AddressBech32(nconsts.HRP, destination)
+// SolveChallenge pays out a valid solution to solver. proof is verified if
+// given, and required if REQUIRE_OWNERSHIP_PROOF is set.
+func (m *Manager) SolveChallenge(ctx context.Context, solver codec.Address, salt []byte, solution []byte, proof *frpc.OwnershipProof) (ids.ID, uint64, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.SolveChallenge")
+	defer span.End()
+
+	start := time.Now()
+	config := m.Config()
+	amount := config.Amount
+	if proof != nil || config.RequireOwnershipProof {
+		if err := frpc.VerifyOwnership(solver, proof, salt, solution, m.ChallengeNonce(salt)); err != nil {
+			m.log.Warn("Rejecting solution", zap.String("address", codec.MustAddressBech32(nconsts.HRP, solver)), zap.Error(err))
+			return ids.Empty, 0, err
+		}
+	}
+	solutionID, difficulty, err := m.reserveSolution(ctx, solver, salt, solution)
 	if err != nil {
-		m.log.Error("Failed to convert address to bech32", zap.Error(err))
 		return ids.Empty, 0, err
 	}
-	_ = m.db.SaveTransaction(tx.ID().String(), destinationAddr, amount)
-	m.log.Info("Transaction saved", zap.String("txID", tx.ID().String()), zap.String("destination", destinationAddr), zap.Uint64("amount", amount))
-
-	return tx.ID(), maxFee, err
-}
 
-func (m *Manager) SolveChallenge(ctx context.Context, solver codec.Address, salt []byte, solution []byte) (ids.ID, uint64, error) {
-	m.l.Lock()
-	defer m.l.Unlock()
-
-	if !bytes.Equal(m.salt, salt) {
-		m.log.Warn("Salt expired")
-		return ids.Empty, 0, errors.New("salt expired")
-	}
-	if !challenge.Verify(salt, solution, m.difficulty) {
-		m.log.Warn("Invalid solution")
-		return ids.Empty, 0, errors.New("invalid solution")
-	}
-	solutionID := utils.ToID(solution)
-	if m.solutions.Contains(solutionID) {
-		m.log.Warn("Duplicate solution")
-		return ids.Empty, 0, errors.New("duplicate solution")
+	// The lock is not held while paying out so the wallets of the pool can
+	// send concurrently
+	saltID, solutionHash := utils.ToID(salt).String(), solutionID.String()
+	if err := m.useSolution(ctx, saltID, solutionHash); err != nil {
+		m.releaseSolution(salt, solutionID)
+		return ids.Empty, 0, err
 	}
-
-	txID, maxFee, err := m.sendFundsRetry(ctx, solver, m.config.Amount)
+	txID, payout, err := m.sendFundsRetry(ctx, solver, ids.Empty, amount)
 	if err != nil {
 		m.log.Error("Failed to send funds", zap.Error(err))
+		// A solution whose transfer may have been accepted stays used
+		if !errors.Is(err, frpc.ErrPayoutUnconfirmed) {
+			m.releaseSolution(salt, solutionID)
+			if err := m.db.RemoveUsedSolution(ctx, saltID, solutionHash); err != nil {
+				m.log.Error("Failed to forget used solution", zap.Error(err))
+			}
+		}
 		return ids.Empty, 0, err
 	}
+	client := frpc.ClientInfoFromContext(ctx)
+	payout.Difficulty = difficulty
+	payout.SaltID = saltID
+	payout.SolutionHash = solutionHash
+	payout.ClientIP = client.IP
+	payout.UserAgent = client.UserAgent
+	m.recordPayout(ctx, payout, start)
 	m.log.Info("Fauceted funds",
 		zap.Stringer("txID", txID),
-		zap.String("max fee", utils.FormatBalance(maxFee, nconsts.Decimals)),
+		zap.String("max fee", utils.FormatBalance(payout.Fee, nconsts.Decimals)),
 		zap.String("destination", codec.MustAddressBech32(nconsts.HRP, solver)),
-		zap.String("amount", utils.FormatBalance(m.config.Amount, nconsts.Decimals)),
+		zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
 	)
-	m.solutions.Add(solutionID)
 
-	if m.solutions.Len() >= m.config.SolutionsPerSalt {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if bytes.Equal(m.salt, salt) && m.solutions.Len() >= m.config.SolutionsPerSalt {
 		// m.difficulty++
 		// m.log.Info("Increasing faucet difficulty", zap.Uint16("new difficulty", m.difficulty))
 		m.lastRotation = time.Now().Unix()
+		m.metrics.lastRotation.Store(m.lastRotation)
 		m.salt, err = challenge.New()
 		if err != nil {
 			m.log.Error("Failed to generate new salt", zap.Error(err))
@@ -290,16 +532,85 @@ func (m *Manager) SolveChallenge(ctx context.Context, solver codec.Address, salt
 		m.log.Info("Salt updated", zap.Uint16("new difficulty", m.difficulty))
 		// m.log.Info("Salt and difficulty updated due to hitting expected solutions", zap.Uint16("new difficulty", m.difficulty))
 	}
-	return txID, m.config.Amount, nil
+	return txID, amount, nil
+}
+
+// reserveSolution validates a solution and records it before paying out, so
+// it cannot be submitted again while the payout is in flight. It returns the
+// ID of the solution and the difficulty it was verified against.
+func (m *Manager) reserveSolution(ctx context.Context, solver codec.Address, salt []byte, solution []byte) (ids.ID, uint16, error) {
+	_, lockSpan := m.tracer.Start(ctx, "Manager.lock")
+	m.l.Lock()
+	lockSpan.End()
+	defer m.l.Unlock()
+
+	if err := m.admit(solver); err != nil {
+		m.log.Warn("Rejecting solution", zap.String("address", codec.MustAddressBech32(nconsts.HRP, solver)), zap.Error(err))
+		return ids.Empty, 0, err
+	}
+	// Once enough solutions are in flight the salt is about to rotate
+	if !bytes.Equal(m.salt, salt) || m.solutions.Len() >= m.config.SolutionsPerSalt {
+		m.log.Warn("Salt expired")
+		return ids.Empty, 0, frpc.ErrSaltExpired
+	}
+	if !challenge.Verify(salt, solution, m.difficulty) {
+		m.log.Warn("Invalid solution")
+		return ids.Empty, 0, frpc.ErrInvalidSolution
+	}
+	solutionID := utils.ToID(solution)
+	if m.solutions.Contains(solutionID) {
+		m.log.Warn("Duplicate solution")
+		return ids.Empty, 0, frpc.ErrDuplicateSolution
+	}
+	m.solutions.Add(solutionID)
+	return solutionID, m.difficulty, nil
+}
+
+// admit returns why a payout to destination is refused, if paused or denied.
+// m.l must be held.
+func (m *Manager) admit(destination codec.Address) error {
+	if err := m.pauseError(); err != nil {
+		return err
+	}
+	if m.denied.Contains(destination) {
+		return frpc.ErrAddressDenied
+	}
+	return nil
+}
+
+// pauseError returns ErrMaintenance if payouts are paused. m.l must be held.
+func (m *Manager) pauseError() error {
+	if !m.pause.Paused {
+		return nil
+	}
+	merr := frpc.ErrMaintenance.WithDetail(m.pause.Message)
+	if wait := time.Until(time.Unix(m.pause.ResumeAt, 0)); m.pause.ResumeAt > 0 && wait > 0 {
+		merr = merr.WithRetryAfter(wait)
+	}
+	return merr
+}
+
+// releaseSolution forgets a reserved solution whose payout failed, so it can
+// be submitted again
+func (m *Manager) releaseSolution(salt []byte, solutionID ids.ID) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if bytes.Equal(m.salt, salt) {
+		m.solutions.Remove(solutionID)
+	}
 }
 
 func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.UpdateNuklaiRPC")
+	defer span.End()
+
 	m.l.Lock()
 	defer m.l.Unlock()
 
 	m.log.Info("Updating nuklaiRPC URL", zap.String("old URL", m.config.NuklaiRPC), zap.String("new URL", newNuklaiRPCUrl))
 
-	m.config.NuklaiRPC = newNuklaiRPCUrl
+	m.config = m.config.WithNuklaiRPC(newNuklaiRPCUrl)
 
 	cli := rpc.NewJSONRPCClient(newNuklaiRPCUrl)
 	networkID, _, chainID, err := cli.Network(ctx)
@@ -309,20 +620,30 @@ func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string) e
 	}
 	m.log.Info("Fetched network details", zap.Uint32("network ID", networkID), zap.String("chain ID", chainID.String()))
 
-	scli, err := rpc.NewWebSocketClient(
-		newNuklaiRPCUrl,
-		rpc.DefaultHandshakeTimeout,
-		pubsub.MaxPendingMessages,
-		pubsub.MaxReadMessageSize,
-	)
-	if err != nil {
-		m.log.Error("Failed to create WebSocket client", zap.Error(err))
-		return fmt.Errorf("failed to create WebSocket client: %w", err)
+	wallets := m.wallets.wallets
+	if m.treasury != nil {
+		wallets = append(wallets[:len(wallets):len(wallets)], m.treasury)
+	}
+	for _, w := range wallets {
+		w.l.Lock()
+		err := w.connect(newNuklaiRPCUrl)
+		w.l.Unlock()
+		if err != nil {
+			m.log.Error("Failed to create WebSocket client", zap.Error(err))
+			return fmt.Errorf("failed to create WebSocket client: %w", err)
+		}
 	}
-	m.scli = scli
 
 	m.cli = cli
 	m.ncli = nrpc.NewJSONRPCClient(newNuklaiRPCUrl, networkID, chainID)
+	if chainID != m.chainID {
+		// Payouts on the new chain follow its own pause state
+		m.chainID = chainID
+		if err := m.loadPauseState(ctx); err != nil {
+			m.log.Error("Failed to load pause state", zap.Error(err))
+			return fmt.Errorf("failed to load pause state: %w", err)
+		}
+	}
 
 	m.salt, err = challenge.New()
 	if err != nil {
@@ -331,19 +652,24 @@ func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string) e
 	}
 	m.solutions = set.NewSet[ids.ID](m.config.SolutionsPerSalt)
 	m.difficulty = m.config.StartDifficulty
+	m.metrics.difficulty.Set(float64(m.difficulty))
 	m.lastRotation = time.Now().Unix()
+	m.metrics.lastRotation.Store(m.lastRotation)
 
-	bal, err := m.ncli.Balance(ctx, m.config.AddressBech32(), nconsts.Symbol)
+	bal, err := m.refreshBalances(ctx, m.ncli)
 	if err != nil {
 		return err
 	}
-	m.t = timer.NewTimer(m.updateDifficulty)
+	// The timer dispatched by Run keeps rotating the salt, it only restarts
+	// its countdown from the new salt
+	m.t.Cancel()
+	m.t.SetTimeoutIn(time.Duration(m.config.TargetDurationPerSalt) * time.Second)
 
 	m.log.Info("RPC client has been updated and manager reinitialized",
 		zap.String("new RPC URL", newNuklaiRPCUrl),
 		zap.Uint32("network ID", networkID),
 		zap.String("chain ID", chainID.String()),
-		zap.String("address", m.config.AddressBech32()),
+		zap.String("address", m.wallets.primary().bech32),
 		zap.Uint16("difficulty", m.difficulty),
 		zap.String("balance", utils.FormatBalance(bal, nconsts.Decimals)),
 	)
@@ -351,7 +677,239 @@ func (m *Manager) UpdateNuklaiRPC(ctx context.Context, newNuklaiRPCUrl string) e
 	return nil
 }
 
-// Config returns the configuration of the manager
+// Pause stops payouts until Resume is called. The state is persisted so the
+// faucet stays paused across restarts.
+func (m *Manager) Pause(ctx context.Context, message string, resumeAt int64) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	state := database.PauseState{Paused: true, Message: message, ResumeAt: resumeAt}
+	if err := m.db.SavePauseState(ctx, m.chainID.String(), &state); err != nil {
+		m.log.Error("Failed to persist pause state", zap.Error(err))
+		return fmt.Errorf("failed to persist pause state: %w", err)
+	}
+	m.pause = state
+	m.log.Info("Faucet paused", zap.String("message", message), zap.Int64("resumeAt", resumeAt))
+	return nil
+}
+
+// Resume re-enables payouts after a Pause
+func (m *Manager) Resume(ctx context.Context) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	state := database.PauseState{}
+	if err := m.db.SavePauseState(ctx, m.chainID.String(), &state); err != nil {
+		m.log.Error("Failed to persist pause state", zap.Error(err))
+		return fmt.Errorf("failed to persist pause state: %w", err)
+	}
+	m.pause = state
+	m.log.Info("Faucet resumed")
+	return nil
+}
+
+// loadPauseState restores the persisted pause state of the chain. A faucet
+// upgraded from a version that did not record chain IDs keeps the state it
+// saved then until it is changed.
+func (m *Manager) loadPauseState(ctx context.Context) error {
+	pause, err := m.db.GetPauseState(ctx, m.chainID.String())
+	if err != nil {
+		return err
+	}
+	if pause.UpdatedAt == 0 {
+		if pause, err = m.db.GetPauseState(ctx, ""); err != nil {
+			return err
+		}
+	}
+	m.pause = *pause
+	return nil
+}
+
+// GetPauseState returns whether payouts are paused along with the
+// maintenance message and expected resume time
+func (m *Manager) GetPauseState(_ context.Context) (bool, string, int64, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.pause.Paused, m.pause.Message, m.pause.ResumeAt, nil
+}
+
+// GetBalance returns the current balance of the faucet, summed over every
+// wallet of the pool
+func (m *Manager) GetBalance(ctx context.Context) (uint64, error) {
+	m.l.RLock()
+	ncli := m.ncli
+	m.l.RUnlock()
+
+	return m.refreshBalances(ctx, ncli)
+}
+
+// refreshBalances fetches the balance of every wallet and returns their sum
+func (m *Manager) refreshBalances(ctx context.Context, ncli *nrpc.JSONRPCClient) (uint64, error) {
+	var total uint64
+	for _, w := range m.wallets.wallets {
+		balCtx, span := m.tracer.Start(ctx, "chain.Balance", oteltrace.WithAttributes(attribute.String("address", w.bech32)))
+		bal, err := ncli.Balance(balCtx, w.bech32, nconsts.Symbol)
+		span.End()
+		if err != nil {
+			return 0, err
+		}
+		m.wallets.setBalance(w, bal)
+		total += bal
+	}
+	m.metrics.balance.Set(float64(total))
+	return total, nil
+}
+
+// GetTransactionStats aggregates the payouts recorded on the chain of the
+// faucet
+func (m *Manager) GetTransactionStats(ctx context.Context) (*database.Stats, error) {
+	return m.db.GetStats(ctx, m.ChainID().String())
+}
+
+// GetTransactions returns the most recent payouts on the chain of the faucet,
+// optionally filtered by destination
+func (m *Manager) GetTransactions(ctx context.Context, destination string, limit int) ([]database.Transaction, error) {
+	return m.db.SearchTransactions(ctx, &database.TransactionFilter{ChainID: m.ChainID().String(), Destination: destination, Limit: limit})
+}
+
+// SearchTransactions returns the payouts on the chain of the faucet matching
+// filter, the most recent first
+func (m *Manager) SearchTransactions(ctx context.Context, filter *database.TransactionFilter) ([]database.Transaction, error) {
+	chainID := m.ChainID().String()
+	if filter.ChainID != "" && filter.ChainID != chainID {
+		return nil, nil
+	}
+	chainFilter := *filter
+	chainFilter.ChainID = chainID
+	return m.db.SearchTransactions(ctx, &chainFilter)
+}
+
+// loadDeniedAddresses replaces the cached deny list with the one in the
+// database. The deny list is shared by every network, so it is reloaded
+// every deniedRefreshInterval to pick up the changes made through the other
+// networks.
+func (m *Manager) loadDeniedAddresses(ctx context.Context) error {
+	// The lock is held while reading so a concurrent DenyAddress is not
+	// overwritten by an older list
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	records, err := m.db.GetDeniedAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	denied := set.NewSet[codec.Address](len(records))
+	for _, d := range records {
+		addr, err := codec.ParseAddressBech32(nconsts.HRP, d.Address)
+		if err != nil {
+			m.log.Warn("Skipping invalid denied address", zap.String("address", d.Address), zap.Error(err))
+			continue
+		}
+		denied.Add(addr)
+	}
+	m.denied = denied
+	return nil
+}
+
+// refreshDeniedAddresses reloads the deny list every deniedRefreshInterval
+// until ctx is done
+func (m *Manager) refreshDeniedAddresses(ctx context.Context) {
+	ticker := time.NewTicker(deniedRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.loadDeniedAddresses(ctx); err != nil {
+			m.log.Warn("Failed to refresh deny list", zap.Error(err))
+		}
+	}
+}
+
+// DenyAddress refuses any further payout to addr
+func (m *Manager) DenyAddress(ctx context.Context, addr codec.Address, reason string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if err := m.db.AddDeniedAddress(ctx, codec.MustAddressBech32(nconsts.HRP, addr), reason); err != nil {
+		return err
+	}
+	m.denied.Add(addr)
+	m.log.Info("Address denied", zap.String("address", codec.MustAddressBech32(nconsts.HRP, addr)), zap.String("reason", reason))
+	return nil
+}
+
+// RemoveDeniedAddress allows payouts to addr again
+func (m *Manager) RemoveDeniedAddress(ctx context.Context, addr codec.Address) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if err := m.db.RemoveDeniedAddress(ctx, codec.MustAddressBech32(nconsts.HRP, addr)); err != nil {
+		return err
+	}
+	m.denied.Remove(addr)
+	m.log.Info("Address removed from deny list", zap.String("address", codec.MustAddressBech32(nconsts.HRP, addr)))
+	return nil
+}
+
+func (m *Manager) GetDeniedAddresses(ctx context.Context) ([]database.DeniedAddress, error) {
+	return m.db.GetDeniedAddresses(ctx)
+}
+
+// ChainID returns the chain the faucet pays out on
+func (m *Manager) ChainID() ids.ID {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.chainID
+}
+
+// Config returns the configuration of the manager. The returned config is
+// never modified, a reload replaces it.
 func (m *Manager) Config() *fconfig.Config {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
 	return m.config
 }
+
+// Reload switches to the live settings of config, such as the payout amount,
+// the difficulty, the balance thresholds and the admin keys, keeping the
+// current salt. Changes to other settings are rejected and logged since they
+// require a restart.
+func (m *Manager) Reload(config *fconfig.Config) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	merged, applied, rejected, err := m.config.Reload(config)
+	if err != nil {
+		return err
+	}
+	if len(rejected) > 0 {
+		m.log.Warn("Rejecting config changes that require a restart", zap.Strings("settings", rejected))
+	}
+	if len(applied) == 0 {
+		m.log.Info("Config reloaded, no live setting changed")
+		return nil
+	}
+
+	if merged.StartDifficulty != m.config.StartDifficulty {
+		m.difficulty = merged.StartDifficulty
+		m.metrics.difficulty.Set(float64(m.difficulty))
+	}
+	if merged.TargetDurationPerSalt != m.config.TargetDurationPerSalt {
+		// The running timer was armed with the old duration, the salt now
+		// rotates once the new one has passed since the last rotation
+		remaining := m.lastRotation + merged.TargetDurationPerSalt - time.Now().Unix()
+		m.t.Cancel()
+		m.t.SetTimeoutIn(time.Duration(max(remaining, 0)) * time.Second)
+	}
+	m.notifier = alert.NewNotifier(merged.BalanceWebhooks)
+	m.config = merged
+	m.log.Info("Config reloaded", zap.Strings("applied", applied), zap.Uint16("difficulty", m.difficulty))
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"github.com/prometheus/client_golang/prometheus"
+
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+)
+
+const namespace = "faucet"
+
+// Payout outcomes
+const (
+	payoutSuccess           = "success"
+	payoutNetworkFeeTooHigh = "network_fee_too_high"
+	payoutInsufficientFunds = "insufficient_funds"
+	payoutFailed            = "failed"
+)
+
+type metrics struct {
+	payouts             *prometheus.CounterVec
+	websocketReconnects *prometheus.CounterVec
+	refills             *prometheus.CounterVec
+	balance             prometheus.Gauge
+	depletion           prometheus.Gauge
+	walletBalance       *prometheus.GaugeVec
+	walletInFlight      *prometheus.GaugeVec
+	rebalances          *prometheus.CounterVec
+	difficulty          prometheus.Gauge
+	payoutLatency       prometheus.Histogram
+	payoutFee           prometheus.Histogram
+
+	lastRotation atomic.Int64 // unix seconds, read when salt age is scraped
+}
+
+func newMetrics(r prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		payouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payouts_total",
+			Help:      "number of payouts by outcome",
+		}, []string{"outcome"}),
+		websocketReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "websocket_reconnects_total",
+			Help:      "number of WebSocket reconnection attempts by result",
+		}, []string{"result"}),
+		refills: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refills_total",
+			Help:      "number of treasury refill attempts by status",
+		}, []string{"status"}),
+		balance: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance",
+			Help:      "last observed faucet balance in base units",
+		}),
+		walletBalance: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "wallet_balance",
+			Help:      "last observed balance of each faucet wallet in base units",
+		}, []string{"address"}),
+		walletInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "wallet_in_flight",
+			Help:      "transactions in flight for each faucet wallet",
+		}, []string{"address"}),
+		rebalances: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rebalances_total",
+			Help:      "number of transfers between faucet wallets by result",
+		}, []string{"result"}),
+		depletion: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_depletion_seconds",
+			Help:      "estimated seconds until the balance runs out at the payout rate of the last 24 hours, 0 if unknown",
+		}),
+		difficulty: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "difficulty",
+			Help:      "current challenge difficulty",
+		}),
+		payoutLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "payout_latency_seconds",
+			Help:      "time spent sending a payout, including retries",
+			Buckets:   prometheus.ExponentialBuckets(0.25, 2, 10),
+		}),
+		payoutFee: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "payout_fee",
+			Help:      "max fee of successful payouts in base units",
+			Buckets:   prometheus.ExponentialBuckets(1_000, 4, 10),
+		}),
+	}
+	saltAge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "salt_age_seconds",
+		Help:      "seconds since the salt was last rotated",
+	}, func() float64 {
+		return float64(time.Now().Unix() - m.lastRotation.Load())
+	})
+
+	errs := wrappers.Errs{}
+	errs.Add(
+		r.Register(m.payouts),
+		r.Register(m.websocketReconnects),
+		r.Register(m.refills),
+		r.Register(m.balance),
+		r.Register(m.depletion),
+		r.Register(m.walletBalance),
+		r.Register(m.walletInFlight),
+		r.Register(m.rebalances),
+		r.Register(m.difficulty),
+		r.Register(m.payoutLatency),
+		r.Register(m.payoutFee),
+		r.Register(saltAge),
+	)
+	return m, errs.Err
+}
+
+// observePayout records the latency, outcome and fee of a payout
+func (m *metrics) observePayout(latency time.Duration, maxFee uint64, err error) {
+	m.payoutLatency.Observe(latency.Seconds())
+	switch {
+	case err == nil:
+		m.payouts.WithLabelValues(payoutSuccess).Inc()
+		m.payoutFee.Observe(float64(maxFee))
+	case errors.Is(err, frpc.ErrNetworkFeeTooHigh):
+		m.payouts.WithLabelValues(payoutNetworkFeeTooHigh).Inc()
+	case errors.Is(err, frpc.ErrInsufficientFunds):
+		m.payouts.WithLabelValues(payoutInsufficientFunds).Inc()
+	default:
+		m.payouts.WithLabelValues(payoutFailed).Inc()
+	}
+}
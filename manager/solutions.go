@@ -0,0 +1,57 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+)
+
+// solutionPruneInterval is how often expired used solutions are removed
+const solutionPruneInterval = 10 * time.Minute
+
+// useSolution durably records a solution before paying it out. The in-memory
+// set of the salt only covers this process until the next rotation, the
+// database also rejects replays across restarts and instances.
+func (m *Manager) useSolution(ctx context.Context, saltID, solutionHash string) error {
+	ctx, span := m.tracer.Start(ctx, "Manager.useSolution")
+	defer span.End()
+
+	expiresAt := time.Now().Add(time.Duration(m.Config().SolutionTTL) * time.Second).Unix()
+	added, err := m.db.AddUsedSolution(ctx, saltID, solutionHash, expiresAt)
+	if err != nil {
+		m.log.Error("Failed to record used solution", zap.Error(err))
+		return err
+	}
+	if !added {
+		m.log.Warn("Replayed solution", zap.String("saltID", saltID), zap.String("solutionHash", solutionHash))
+		return frpc.ErrDuplicateSolution
+	}
+	return nil
+}
+
+// pruneSolutions removes expired used solutions every solutionPruneInterval
+// until ctx is done
+func (m *Manager) pruneSolutions(ctx context.Context) {
+	ticker := time.NewTicker(solutionPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		pruned, err := m.db.PruneUsedSolutions(ctx, time.Now().Unix())
+		if err != nil {
+			m.log.Warn("Failed to prune used solutions", zap.Error(err))
+		} else if pruned > 0 {
+			m.log.Info("Pruned used solutions", zap.Int64("count", pruned))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
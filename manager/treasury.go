@@ -0,0 +1,80 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+const refillCapped = "capped"
+
+// refill tops up the faucet from the treasury when bal is below
+// RefillThreshold, as long as the refills of the last 24 hours stay within
+// RefillDailyCap. It reports whether funds were transferred.
+func (m *Manager) refill(ctx context.Context, bal uint64) bool {
+	config := m.Config()
+	if m.treasury == nil || bal >= config.RefillThreshold {
+		return false
+	}
+	ctx, span := m.tracer.Start(ctx, "Manager.refill")
+	defer span.End()
+
+	chainID := m.ChainID().String()
+	refilled, err := m.db.GetRefilledSince(ctx, chainID, time.Now().Add(-24*time.Hour).Unix())
+	if err != nil {
+		m.log.Warn("Failed to fetch refilled amount", zap.Error(err))
+		return false
+	}
+	if refilled+config.RefillAmount > config.RefillDailyCap {
+		m.metrics.refills.WithLabelValues(refillCapped).Inc()
+		m.log.Warn("Skipping refill, daily cap reached",
+			zap.String("refilled", utils.FormatBalance(refilled, nconsts.Decimals)),
+			zap.String("cap", utils.FormatBalance(config.RefillDailyCap, nconsts.Decimals)),
+		)
+		return false
+	}
+
+	txID, _, err := m.transfer(ctx, m.treasury, m.wallets.primary().address, ids.Empty, config.RefillAmount)
+
+	// An unconfirmed refill may have been accepted, so it counts toward the
+	// daily cap
+	record := &database.Refill{ChainID: chainID, Amount: config.RefillAmount, Status: database.RefillSucceeded}
+	switch {
+	case errors.Is(err, frpc.ErrPayoutUnconfirmed):
+		record.Status = database.RefillUnconfirmed
+		record.TxID = txID.String()
+		record.Error = err.Error()
+		m.log.Error("Refill from treasury not confirmed", zap.Stringer("txID", txID), zap.Error(err))
+	case err != nil:
+		record.Status = database.RefillFailed
+		record.Error = err.Error()
+		m.log.Error("Failed to refill from treasury", zap.Error(err))
+	default:
+		record.TxID = txID.String()
+		m.log.Info("Refilled from treasury",
+			zap.Stringer("txID", txID),
+			zap.String("amount", utils.FormatBalance(config.RefillAmount, nconsts.Decimals)),
+		)
+	}
+	m.metrics.refills.WithLabelValues(record.Status).Inc()
+	if err := m.db.SaveRefill(ctx, record); err != nil {
+		m.log.Error("Failed to record refill", zap.Error(err))
+	}
+	return record.Status == database.RefillSucceeded
+}
+
+// GetRefillStats aggregates the refill attempts recorded on the chain of the
+// faucet
+func (m *Manager) GetRefillStats(ctx context.Context) (*database.RefillStats, error) {
+	return m.db.GetRefillStats(ctx, m.ChainID().String())
+}
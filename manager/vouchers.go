@@ -0,0 +1,211 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklai-faucet/database"
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxVoucherBatch is the largest number of vouchers minted at once
+	MaxVoucherBatch = 10_000
+
+	// voucherCodeBytes of randomness make a code of 16 base32 characters
+	voucherCodeBytes = 10
+	batchIDBytes     = 8
+)
+
+// voucherErrors maps the store failures to the RPC catalog
+var voucherErrors = map[error]*frpc.Error{
+	database.ErrVoucherNotFound:  frpc.ErrVoucherNotFound,
+	database.ErrVoucherRevoked:   frpc.ErrVoucherRevoked,
+	database.ErrVoucherExpired:   frpc.ErrVoucherExpired,
+	database.ErrVoucherExhausted: frpc.ErrVoucherExhausted,
+	database.ErrVoucherRedeemed:  frpc.ErrVoucherRedeemed,
+}
+
+// MintVouchers creates a batch of count vouchers on the current chain, each
+// paying amount of asset, ids.Empty for the native asset, up to maxUses
+// times until expiresAt (unix seconds, 0 for never). A zero amount pays the
+// configured amount and zero maxUses allows a single use. It returns the ID
+// of the batch and its vouchers.
+func (m *Manager) MintVouchers(ctx context.Context, createdBy string, count int, amount uint64, asset ids.ID, maxUses uint32, expiresAt int64) (string, []database.Voucher, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.MintVouchers")
+	defer span.End()
+
+	if count <= 0 || count > MaxVoucherBatch {
+		return "", nil, frpc.ErrInvalidArgument.WithDetail(fmt.Sprintf("voucher count must be between 1 and %d", MaxVoucherBatch))
+	}
+	if expiresAt != 0 && expiresAt <= time.Now().Unix() {
+		return "", nil, frpc.ErrInvalidArgument.WithDetail("voucher expiry must be in the future")
+	}
+	if amount == 0 {
+		amount = m.Config().Amount
+	}
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	var assetID string
+	if asset != ids.Empty {
+		assetID = asset.String()
+	}
+
+	batchID, err := randomString(batchIDBytes, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	chainID := m.ChainID().String()
+	vouchers := make([]database.Voucher, count)
+	for i := range vouchers {
+		code, err := randomString(voucherCodeBytes, formatVoucherCode)
+		if err != nil {
+			return "", nil, err
+		}
+		vouchers[i] = database.Voucher{
+			Code:      code,
+			BatchID:   batchID,
+			ChainID:   chainID,
+			Asset:     assetID,
+			Amount:    amount,
+			MaxUses:   maxUses,
+			ExpiresAt: expiresAt,
+			CreatedBy: createdBy,
+		}
+	}
+	if err := m.db.SaveVouchers(ctx, vouchers); err != nil {
+		return "", nil, err
+	}
+	m.log.Info("Minted vouchers",
+		zap.String("batchID", batchID),
+		zap.Int("count", count),
+		zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
+		zap.String("asset", assetID),
+		zap.String("createdBy", createdBy),
+	)
+	return batchID, vouchers, nil
+}
+
+// GetVouchers returns the vouchers of batchID
+func (m *Manager) GetVouchers(ctx context.Context, batchID string) ([]database.Voucher, error) {
+	return m.db.GetVouchers(ctx, batchID)
+}
+
+// RevokeVouchers revokes the vouchers of batchID, or the voucher code if
+// batchID is empty, and returns how many were revoked
+func (m *Manager) RevokeVouchers(ctx context.Context, batchID, code string) (int64, error) {
+	if batchID == "" && code == "" {
+		return 0, frpc.ErrInvalidArgument.WithDetail("a batch ID or a voucher code is required")
+	}
+	revoked, err := m.db.RevokeVouchers(ctx, batchID, normalizeVoucherCode(code))
+	if err != nil {
+		return 0, err
+	}
+	m.log.Info("Revoked vouchers", zap.String("batchID", batchID), zap.String("code", code), zap.Int64("revoked", revoked))
+	return revoked, nil
+}
+
+// RedeemVoucher pays the amount of the voucher code to destination. The use
+// is taken before paying out and given back if the payout fails before its
+// transaction is sent.
+func (m *Manager) RedeemVoucher(ctx context.Context, destination codec.Address, code string) (ids.ID, *database.Voucher, error) {
+	ctx, span := m.tracer.Start(ctx, "Manager.RedeemVoucher")
+	defer span.End()
+
+	start := time.Now()
+	address := codec.MustAddressBech32(nconsts.HRP, destination)
+	m.l.RLock()
+	err := m.admit(destination)
+	m.l.RUnlock()
+	if err != nil {
+		m.log.Warn("Rejecting voucher", zap.String("address", address), zap.Error(err))
+		return ids.Empty, nil, err
+	}
+
+	code = normalizeVoucherCode(code)
+	voucher, err := m.db.RedeemVoucher(ctx, m.ChainID().String(), code, address, start.Unix())
+	if err != nil {
+		if ferr, ok := voucherErrors[err]; ok {
+			return ids.Empty, nil, ferr
+		}
+		return ids.Empty, nil, err
+	}
+	asset := ids.Empty
+	if voucher.Asset != "" {
+		if asset, err = ids.FromString(voucher.Asset); err != nil {
+			m.releaseRedemption(ctx, code, address)
+			return ids.Empty, nil, err
+		}
+	}
+
+	txID, payout, err := m.sendFundsRetry(ctx, destination, asset, voucher.Amount)
+	if err != nil {
+		m.log.Error("Failed to send voucher funds", zap.String("code", code), zap.Error(err))
+		// A redemption whose transfer may have been accepted is kept
+		if !errors.Is(err, frpc.ErrPayoutUnconfirmed) {
+			m.releaseRedemption(ctx, code, address)
+		}
+		return ids.Empty, nil, err
+	}
+	client := frpc.ClientInfoFromContext(ctx)
+	payout.ClientIP = client.IP
+	payout.UserAgent = client.UserAgent
+	m.recordPayout(ctx, payout, start)
+	if err := m.db.CompleteRedemption(ctx, code, address, txID.String()); err != nil {
+		m.log.Error("Failed to save voucher redemption", zap.String("code", code), zap.Error(err))
+	}
+	m.log.Info("Redeemed voucher",
+		zap.Stringer("txID", txID),
+		zap.String("code", code),
+		zap.String("destination", address),
+		zap.String("amount", utils.FormatBalance(voucher.Amount, nconsts.Decimals)),
+		zap.String("asset", voucher.Asset),
+	)
+	return txID, voucher, nil
+}
+
+func (m *Manager) releaseRedemption(ctx context.Context, code, address string) {
+	if err := m.db.ReleaseRedemption(ctx, code, address); err != nil {
+		m.log.Error("Failed to release voucher redemption", zap.String("code", code), zap.Error(err))
+	}
+}
+
+// randomString encodes n random bytes
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// formatVoucherCode encodes b in base32 in groups of 4 characters, e.g.
+// ABCD-EFGH-IJKL-MNOP
+func formatVoucherCode(b []byte) string {
+	s := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	var groups []string
+	for len(s) > 4 {
+		groups, s = append(groups, s[:4]), s[4:]
+	}
+	return strings.Join(append(groups, s), "-")
+}
+
+// normalizeVoucherCode accepts codes typed in lower case or with spaces
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
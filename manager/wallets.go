@@ -0,0 +1,260 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package manager
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/pubsub"
+	"github.com/ava-labs/hypersdk/rpc"
+	"github.com/ava-labs/hypersdk/utils"
+	"github.com/nuklai/nuklaivm/auth"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+	"go.uber.org/zap"
+
+	frpc "github.com/nuklai/nuklai-faucet/rpc"
+	"github.com/nuklai/nuklai-faucet/signer"
+)
+
+// wallet is an account the faucet sends from. Each wallet has its own
+// WebSocket connection so the results of transactions sent concurrently by
+// different wallets are never mixed up.
+type wallet struct {
+	factory *signer.Factory
+	address codec.Address
+	bech32  string
+
+	// l is held while a transaction of the wallet is in flight and while
+	// reconnecting
+	l    sync.Mutex
+	scli atomic.Pointer[rpc.WebSocketClient]
+
+	// guarded by walletPool.l
+	inFlight int
+	balance  uint64
+}
+
+func newWallet(s signer.Signer, pk ed25519.PublicKey, uri string) (*wallet, error) {
+	address := auth.NewED25519Address(pk)
+	w := &wallet{
+		factory: signer.NewFactory(s, pk),
+		address: address,
+		bech32:  codec.MustAddressBech32(nconsts.HRP, address),
+	}
+	if err := w.connect(uri); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect replaces the WebSocket connection of the wallet with a new one to
+// uri
+func (w *wallet) connect(uri string) error {
+	scli, err := rpc.NewWebSocketClient(
+		uri,
+		rpc.DefaultHandshakeTimeout,
+		pubsub.MaxPendingMessages,
+		pubsub.MaxReadMessageSize,
+	)
+	if err != nil {
+		return err
+	}
+	if old := w.scli.Swap(scli); old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// connected reports whether the WebSocket connection is open
+func (w *wallet) connected() bool {
+	scli := w.scli.Load()
+	return scli != nil && !scli.Closed()
+}
+
+// walletPool spreads payouts across the faucet wallets. A wallet has at most
+// one transaction in flight and the funded idle wallet with the highest
+// balance is picked first.
+type walletPool struct {
+	l        sync.Mutex
+	wallets  []*wallet
+	released chan struct{} // closed and replaced whenever a wallet is released
+	fee      uint64        // max fee of the last transaction generated
+	metrics  *metrics
+}
+
+func newWalletPool(wallets []*wallet, metrics *metrics) *walletPool {
+	return &walletPool{wallets: wallets, released: make(chan struct{}), metrics: metrics}
+}
+
+// acquire waits for an idle wallet holding at least amount plus the max fee
+// of the last transaction generated, the best estimate of the next one. It
+// fails with ErrInsufficientFunds when no wallet holds enough, busy or not.
+func (p *walletPool) acquire(ctx context.Context, amount uint64) (*wallet, error) {
+	for {
+		p.l.Lock()
+		var (
+			best   *wallet
+			funded bool
+		)
+		for _, w := range p.wallets {
+			if w.balance < amount || w.balance-amount < p.fee {
+				continue
+			}
+			funded = true
+			if w.inFlight == 0 && (best == nil || w.balance > best.balance) {
+				best = w
+			}
+		}
+		if best != nil {
+			p.setInFlight(best, best.inFlight+1)
+			p.l.Unlock()
+			return best, nil
+		}
+		released := p.released
+		p.l.Unlock()
+
+		if !funded {
+			return nil, frpc.ErrInsufficientFunds
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// tryAcquire marks w as busy if it is idle
+func (p *walletPool) tryAcquire(w *wallet) bool {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if w.inFlight > 0 {
+		return false
+	}
+	p.setInFlight(w, 1)
+	return true
+}
+
+// release marks w as idle and wakes up the callers waiting in acquire
+func (p *walletPool) release(w *wallet) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.setInFlight(w, w.inFlight-1)
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+func (p *walletPool) setInFlight(w *wallet, inFlight int) {
+	w.inFlight = inFlight
+	p.metrics.walletInFlight.WithLabelValues(w.bech32).Set(float64(inFlight))
+}
+
+// setBalance records the last observed balance of w. Wallets outside of the
+// pool are ignored.
+func (p *walletPool) setBalance(w *wallet, balance uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	for _, pw := range p.wallets {
+		if pw == w {
+			w.balance = balance
+			p.metrics.walletBalance.WithLabelValues(w.bech32).Set(float64(balance))
+			return
+		}
+	}
+}
+
+// setFee records the max fee of the last transaction generated
+func (p *walletPool) setFee(fee uint64) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.fee = fee
+}
+
+// balances returns the last observed balance of every wallet, in pool order
+func (p *walletPool) balances() []uint64 {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	balances := make([]uint64, len(p.wallets))
+	for i, w := range p.wallets {
+		balances[i] = w.balance
+	}
+	return balances
+}
+
+// primary returns the wallet refills are sent to and whose address is
+// advertised as the faucet address
+func (p *walletPool) primary() *wallet {
+	return p.wallets[0]
+}
+
+// addresses returns the address of every wallet, in pool order
+func (p *walletPool) addresses() []codec.Address {
+	addresses := make([]codec.Address, len(p.wallets))
+	for i, w := range p.wallets {
+		addresses[i] = w.address
+	}
+	return addresses
+}
+
+// rebalance moves funds from the richest to the poorest wallet once the
+// poorest holds less than half of the average balance. At most one transfer
+// is made per call and busy wallets are skipped.
+func (m *Manager) rebalance(ctx context.Context) {
+	balances := m.wallets.balances()
+	if len(balances) < 2 {
+		return
+	}
+	var total uint64
+	richest, poorest := 0, 0
+	for i, bal := range balances {
+		total += bal
+		if bal > balances[richest] {
+			richest = i
+		}
+		if bal < balances[poorest] {
+			poorest = i
+		}
+	}
+	target := total / uint64(len(balances))
+	if balances[poorest] >= target/2 {
+		return
+	}
+	amount := min(target-balances[poorest], balances[richest]-target)
+	if amount < m.Config().Amount {
+		return
+	}
+
+	from, to := m.wallets.wallets[richest], m.wallets.wallets[poorest]
+	if !m.wallets.tryAcquire(from) {
+		return
+	}
+	defer m.wallets.release(from)
+
+	ctx, span := m.tracer.Start(ctx, "Manager.rebalance")
+	defer span.End()
+
+	txID, _, err := m.transfer(ctx, from, to.address, ids.Empty, amount)
+	if err != nil {
+		m.metrics.rebalances.WithLabelValues("failure").Inc()
+		m.log.Error("Failed to rebalance wallets", zap.String("from", from.bech32), zap.String("to", to.bech32), zap.Error(err))
+		return
+	}
+	m.metrics.rebalances.WithLabelValues("success").Inc()
+	m.log.Info("Rebalanced wallets",
+		zap.Stringer("txID", txID),
+		zap.String("from", from.bech32),
+		zap.String("to", to.bech32),
+		zap.String("amount", utils.FormatBalance(amount, nconsts.Decimals)),
+	)
+}
@@ -0,0 +1,118 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	fconfig "github.com/nuklai/nuklai-faucet/config"
+	"github.com/nuklai/nuklai-faucet/database"
+)
+
+const migrateUsage = `Usage: nuklai-faucet migrate <command> [flags]
+
+Commands:
+  up [-to version]   apply pending migrations, up to version if set
+  down [-steps n]    roll back the n most recent migrations (default 1)
+  status             list migrations and whether they are applied
+`
+
+// runMigrate implements the migrate subcommand, which manages the database
+// schema without starting the faucet
+func runMigrate(config *fconfig.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return errors.New("missing migrate command")
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	to := 0
+	steps := 1
+	switch command {
+	case "up":
+		fs.IntVar(&to, "to", 0, "apply migrations up to and including this version")
+	case "down":
+		fs.IntVar(&steps, "steps", 1, "number of migrations to roll back")
+	case "status":
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if steps < 1 {
+		return errors.New("-steps must be at least 1")
+	}
+
+	var (
+		dialect database.Dialect
+		dsn     string
+	)
+	switch config.DatabaseBackend {
+	case fconfig.DatabaseSQLite:
+		dialect, dsn = database.SQLite, config.SQLitePath
+	case fconfig.DatabaseMemory:
+		return errors.New("the memory database backend has no schema to migrate")
+	default:
+		dialect, dsn = database.Postgres, config.PostgresDSN()
+	}
+	db, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	migrator, err := database.NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, to)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(rolledBack) == 0 {
+			fmt.Println("no applied migrations")
+		}
+		return err
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	}
+}
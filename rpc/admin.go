@@ -0,0 +1,167 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklai-faucet/config"
+)
+
+const (
+	// AdminAuthWindow is how far the timestamp of a signed admin request may
+	// drift from the server clock
+	AdminAuthWindow = 5 * time.Minute
+
+	maxNonceLength = 64
+)
+
+// AdminAuth authenticates an admin request. The signature is made with a
+// registered admin key over the payload returned by AdminPayload.
+type AdminAuth struct {
+	PublicKey []byte `json:"publicKey"`
+	Timestamp int64  `json:"timestamp"` // unix seconds
+	Nonce     string `json:"nonce"`
+	Signature []byte `json:"signature"`
+}
+
+// AdminPayload returns the bytes signed by an admin key. It covers the chain
+// ID of the network called, so a request cannot be replayed on another
+// network, the RPC method, the params encoded as compact JSON with sorted keys
+// and without the "auth" field, the timestamp and the nonce, separated by
+// newlines.
+func AdminPayload(chainID ids.ID, method string, params any, timestamp int64, nonce string) ([]byte, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "auth")
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%d\n%s", chainID, method, canonical, timestamp, nonce)), nil
+}
+
+// SignAdminRequest fills auth with a fresh timestamp, nonce and signature for
+// calling method with params on the network of chainID, as returned by
+// Challenge
+func SignAdminRequest(key ed25519.PrivateKey, chainID ids.ID, method string, params any, auth *AdminAuth) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	pk := key.PublicKey()
+	auth.PublicKey = pk[:]
+	auth.Timestamp = time.Now().Unix()
+	auth.Nonce = hex.EncodeToString(nonce)
+	payload, err := AdminPayload(chainID, method, params, auth.Timestamp, auth.Nonce)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(payload, key)
+	auth.Signature = sig[:]
+	return nil
+}
+
+// adminNonces holds the nonces of the admin requests accepted by every
+// network served by the process, so a nonce is only used once across them
+var adminNonces = &nonceStore{nonces: map[string]int64{}}
+
+type nonceStore struct {
+	l      sync.Mutex
+	nonces map[string]int64 // public key + nonce -> unix expiry
+}
+
+// use records nonceKey until expiry, false if it is already recorded
+func (s *nonceStore) use(nonceKey string, now, expiry int64) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for nonce, e := range s.nonces {
+		if e < now {
+			delete(s.nonces, nonce)
+		}
+	}
+	if _, ok := s.nonces[nonceKey]; ok {
+		return false
+	}
+	s.nonces[nonceKey] = expiry
+	return true
+}
+
+// adminAuthenticator verifies signed admin requests against the registered
+// admin keys and rejects replayed nonces
+type adminAuthenticator struct {
+	// keys returns the registered admin keys, which a reload may change
+	keys func() []config.AdminKey
+	// chainID returns the chain ID requests must be signed for, which an
+	// RPC update may change
+	chainID func() ids.ID
+
+	nonces *nonceStore
+}
+
+func newAdminAuthenticator(keys func() []config.AdminKey, chainID func() ids.ID) *adminAuthenticator {
+	return &adminAuthenticator{keys: keys, chainID: chainID, nonces: adminNonces}
+}
+
+// lookup returns the admin key matching pk. Every registered key is compared
+// in constant time so the lookup does not leak which keys exist.
+func (a *adminAuthenticator) lookup(pk []byte) *config.AdminKey {
+	var found *config.AdminKey
+	keys := a.keys()
+	for i := range keys {
+		if subtle.ConstantTimeCompare(keys[i].PublicKey[:], pk) == 1 {
+			found = &keys[i]
+		}
+	}
+	return found
+}
+
+// authorize checks that auth is a valid signature of method and params by an
+// admin key with at least role
+func (a *adminAuthenticator) authorize(method string, params any, auth *AdminAuth, role string) (*config.AdminKey, error) {
+	if len(auth.PublicKey) != ed25519.PublicKeyLen || len(auth.Signature) != ed25519.SignatureLen {
+		return nil, ErrUnauthorized
+	}
+	if len(auth.Nonce) == 0 || len(auth.Nonce) > maxNonceLength {
+		return nil, ErrUnauthorized
+	}
+	key := a.lookup(auth.PublicKey)
+	if key == nil {
+		return nil, ErrUnauthorized
+	}
+	now := time.Now()
+	window := int64(AdminAuthWindow / time.Second)
+	if auth.Timestamp < now.Unix()-window || auth.Timestamp > now.Unix()+window {
+		return nil, ErrStaleRequest
+	}
+	payload, err := AdminPayload(a.chainID(), method, params, auth.Timestamp, auth.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(payload, key.PublicKey, ed25519.Signature(auth.Signature)) {
+		return nil, ErrUnauthorized
+	}
+	if !key.HasRole(role) {
+		return nil, ErrForbidden.WithDetail(fmt.Sprintf("%s requires %s", method, role))
+	}
+	if !a.nonces.use(string(auth.PublicKey)+auth.Nonce, now.Unix(), auth.Timestamp+window) {
+		return nil, ErrReplayedRequest
+	}
+	return key, nil
+}
@@ -0,0 +1,275 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklai-faucet/config"
+)
+
+func newTestKey(t *testing.T) ed25519.PrivateKey {
+	key, err := ed25519.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signAt signs method and params like SignAdminRequest, with a fixed
+// timestamp and nonce
+func signAt(t *testing.T, key ed25519.PrivateKey, chainID ids.ID, method string, params any, timestamp int64, nonce string) AdminAuth {
+	payload, err := AdminPayload(chainID, method, params, timestamp, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := key.PublicKey()
+	sig := ed25519.Sign(payload, key)
+	return AdminAuth{PublicKey: pk[:], Timestamp: timestamp, Nonce: nonce, Signature: sig[:]}
+}
+
+func TestAdminPayload(t *testing.T) {
+	chainID := ids.GenerateTestID()
+	args := &PauseArgs{Message: "upgrade", ResumeAt: 10}
+
+	payload, err := AdminPayload(chainID, "pause", args, 100, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chainID.String() + "\npause\n" + `{"message":"upgrade","resumeAt":10}` + "\n100\nn"
+	if string(payload) != want {
+		t.Fatalf("payload = %q, want %q", payload, want)
+	}
+
+	// The auth field holds the signature, so it cannot be signed
+	args.Auth = AdminAuth{PublicKey: []byte{1}, Timestamp: 5, Nonce: "x", Signature: []byte{2}}
+	withAuth, err := AdminPayload(chainID, "pause", args, 100, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(withAuth, payload) {
+		t.Fatalf("payload with auth = %q, want %q", withAuth, payload)
+	}
+
+	other, err := AdminPayload(ids.GenerateTestID(), "pause", args, 100, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other, payload) {
+		t.Fatal("payload does not depend on the chain ID")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	chainID := ids.GenerateTestID()
+	viewer, operator, superadmin := newTestKey(t), newTestKey(t), newTestKey(t)
+	keys := []config.AdminKey{
+		{Name: "viewer", Role: config.RoleViewer, PublicKey: viewer.PublicKey()},
+		{Name: "operator", Role: config.RoleOperator, PublicKey: operator.PublicKey()},
+		{Name: "superadmin", Role: config.RoleSuperAdmin, PublicKey: superadmin.PublicKey()},
+	}
+	now := time.Now().Unix()
+	window := int64(AdminAuthWindow / time.Second)
+
+	tests := []struct {
+		name string
+		key  ed25519.PrivateKey
+		role string
+		// sign returns the auth of args, which it may change afterwards
+		sign func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth
+		want error
+	}{
+		{
+			name: "valid",
+			key:  operator,
+			role: config.RoleOperator,
+		},
+		{
+			name: "higher role",
+			key:  superadmin,
+			role: config.RoleOperator,
+		},
+		{
+			name: "tampered params",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				auth := signAt(t, key, chainID, "pause", args, now, "nonce")
+				args.Message = "tampered"
+				return auth
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "other method",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, chainID, "resume", args, now, "nonce")
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "wrong chain ID",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, ids.GenerateTestID(), "pause", args, now, "nonce")
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "timestamp too old",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, chainID, "pause", args, now-window-10, "nonce")
+			},
+			want: ErrStaleRequest,
+		},
+		{
+			name: "timestamp in the future",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, chainID, "pause", args, now+window+10, "nonce")
+			},
+			want: ErrStaleRequest,
+		},
+		{
+			name: "empty nonce",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, chainID, "pause", args, now, "")
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "oversized nonce",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				return signAt(t, key, chainID, "pause", args, now, strings.Repeat("n", maxNonceLength+1))
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "unknown key",
+			key:  newTestKey(t),
+			role: config.RoleViewer,
+			want: ErrUnauthorized,
+		},
+		{
+			name: "truncated signature",
+			key:  operator,
+			role: config.RoleOperator,
+			sign: func(key ed25519.PrivateKey, args *PauseArgs) AdminAuth {
+				auth := signAt(t, key, chainID, "pause", args, now, "nonce")
+				auth.Signature = auth.Signature[1:]
+				return auth
+			},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "viewer calling operator method",
+			key:  viewer,
+			role: config.RoleOperator,
+			want: ErrForbidden,
+		},
+		{
+			name: "viewer calling superadmin method",
+			key:  viewer,
+			role: config.RoleSuperAdmin,
+			want: ErrForbidden,
+		},
+		{
+			name: "operator calling superadmin method",
+			key:  operator,
+			role: config.RoleSuperAdmin,
+			want: ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAdminAuthenticator(func() []config.AdminKey { return keys }, func() ids.ID { return chainID })
+			a.nonces = &nonceStore{nonces: map[string]int64{}}
+
+			args := &PauseArgs{Message: "upgrade"}
+			if tt.sign != nil {
+				args.Auth = tt.sign(tt.key, args)
+			} else {
+				args.Auth = signAt(t, tt.key, chainID, "pause", args, now, "nonce")
+			}
+			key, err := a.authorize("pause", args, &args.Auth, tt.role)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("authorize = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authorize: %v", err)
+			}
+			if pk := tt.key.PublicKey(); key.PublicKey != pk {
+				t.Fatalf("authorized key %s, want the signing key", key.Name)
+			}
+		})
+	}
+}
+
+func TestAuthorizeReplay(t *testing.T) {
+	chainID := ids.GenerateTestID()
+	key := newTestKey(t)
+	keys := []config.AdminKey{{Name: "operator", Role: config.RoleOperator, PublicKey: key.PublicKey()}}
+	a := newAdminAuthenticator(func() []config.AdminKey { return keys }, func() ids.ID { return chainID })
+	a.nonces = &nonceStore{nonces: map[string]int64{}}
+
+	args := &PauseArgs{Message: "upgrade"}
+	args.Auth = signAt(t, key, chainID, "pause", args, time.Now().Unix(), "nonce")
+	if _, err := a.authorize("pause", args, &args.Auth, config.RoleOperator); err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if _, err := a.authorize("pause", args, &args.Auth, config.RoleOperator); !errors.Is(err, ErrReplayedRequest) {
+		t.Fatalf("replayed authorize = %v, want %v", err, ErrReplayedRequest)
+	}
+
+	// A rejected request does not use up its nonce
+	args.Auth = signAt(t, key, chainID, "pause", args, time.Now().Unix(), "other")
+	if _, err := a.authorize("pause", args, &args.Auth, config.RoleSuperAdmin); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("authorize = %v, want %v", err, ErrForbidden)
+	}
+	if _, err := a.authorize("pause", args, &args.Auth, config.RoleOperator); err != nil {
+		t.Fatalf("authorize after forbidden: %v", err)
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	s := &nonceStore{nonces: map[string]int64{}}
+	if !s.use("a", 100, 200) {
+		t.Fatal("first use of a rejected")
+	}
+	if s.use("a", 150, 250) {
+		t.Fatal("second use of a accepted before it expired")
+	}
+	if !s.use("b", 150, 250) {
+		t.Fatal("first use of b rejected")
+	}
+	// a expired at 200, so it is dropped and may be used again
+	if !s.use("a", 201, 300) {
+		t.Fatal("use of a rejected after it expired")
+	}
+	if _, ok := s.nonces["b"]; !ok {
+		t.Fatal("b dropped before it expired")
+	}
+	if s.use("b", 201, 300) {
+		t.Fatal("second use of b accepted before it expired")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// AirdropRecipient is a line of an airdrop list
+type AirdropRecipient struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount,omitempty"` // 0 for the configured amount
+}
+
+// ParseAirdropCSV reads recipients from CSV records of an address and an
+// optional amount in base units. A first record starting with "address" is
+// a header and is not counted as a line.
+func ParseAirdropCSV(data string) ([]AirdropRecipient, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var recipients []AirdropRecipient
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return recipients, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		address := strings.TrimSpace(record[0])
+		if first && strings.EqualFold(address, "address") {
+			continue
+		}
+		line := len(recipients) + 1
+		if len(record) > 2 {
+			return nil, fmt.Errorf("line %d: expected an address and an amount, got %d fields", line, len(record))
+		}
+		recipient := AirdropRecipient{Address: address}
+		if len(record) == 2 && strings.TrimSpace(record[1]) != "" {
+			if recipient.Amount, err = strconv.ParseUint(strings.TrimSpace(record[1]), 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount %q", line, record[1])
+			}
+		}
+		recipients = append(recipients, recipient)
+	}
+}
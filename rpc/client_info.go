@@ -0,0 +1,54 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// maxUserAgentLength bounds the user agent recorded with a payout
+const maxUserAgentLength = 256
+
+type clientInfoKey struct{}
+
+// ClientInfo identifies the HTTP client a request came from. It is passed to
+// the manager in the request context so payouts can be recorded with it.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// WithClientInfo returns a copy of ctx carrying info
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client info carried by ctx, or an empty
+// one if there is none
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// newClientInfo reads the client info of req. The left-most address of the
+// X-Forwarded-For header is only used if trustForwardedFor is set, since
+// clients can set it to anything when the faucet is not behind a proxy.
+func newClientInfo(req *http.Request, trustForwardedFor bool) ClientInfo {
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if forwarded := req.Header.Get("X-Forwarded-For"); trustForwardedFor && forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		ip = strings.TrimSpace(first)
+	}
+	userAgent := req.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return ClientInfo{IP: ip, UserAgent: userAgent}
+}
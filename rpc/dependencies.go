@@ -9,12 +9,41 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/hypersdk/codec"
 	"github.com/nuklai/nuklai-faucet/config"
+	"github.com/nuklai/nuklai-faucet/database"
 )
 
 type Manager interface {
 	GetFaucetAddress(context.Context) (codec.Address, error)
+	GetFaucetAddresses(context.Context) ([]codec.Address, error)
+	ChainID() ids.ID
 	GetChallenge(context.Context) ([]byte, uint16, error)
-	SolveChallenge(context.Context, codec.Address, []byte, []byte) (ids.ID, uint64, error)
+	ChallengeNonce([]byte) []byte
+	SolveChallenge(context.Context, codec.Address, []byte, []byte, *OwnershipProof) (ids.ID, uint64, error)
 	UpdateNuklaiRPC(context.Context, string) error
+	Pause(context.Context, string, int64) error
+	Resume(context.Context) error
+	GetPauseState(context.Context) (bool, string, int64, error)
+	GetBalance(context.Context) (uint64, error)
+	GetTransactionStats(context.Context) (*database.Stats, error)
+	GetRefillStats(context.Context) (*database.RefillStats, error)
+	GetTransactions(context.Context, string, int) ([]database.Transaction, error)
+	SearchTransactions(context.Context, *database.TransactionFilter) ([]database.Transaction, error)
+	DenyAddress(context.Context, codec.Address, string) error
+	RemoveDeniedAddress(context.Context, codec.Address) error
+	GetDeniedAddresses(context.Context) ([]database.DeniedAddress, error)
+	MintVouchers(context.Context, string, int, uint64, ids.ID, uint32, int64) (string, []database.Voucher, error)
+	GetVouchers(context.Context, string) ([]database.Voucher, error)
+	RevokeVouchers(context.Context, string, string) (int64, error)
+	RedeemVoucher(context.Context, codec.Address, string) (ids.ID, *database.Voucher, error)
+	CreateAPIKey(context.Context, string, string, uint64, uint32, []string, []string) (string, *database.APIKey, error)
+	GetAPIKeys(context.Context) ([]database.APIKey, error)
+	RevokeAPIKey(context.Context, string) (bool, error)
+	GetAPIKeyUsage(context.Context, string, int) ([]database.APIKeyUsage, error)
+	ClaimWithAPIKey(context.Context, string, codec.Address, ids.ID, uint64) (ids.ID, uint64, error)
+	CreateAirdrop(context.Context, string, string, ids.ID, []AirdropRecipient) (*database.Airdrop, []database.AirdropRow, error)
+	GetAirdrop(context.Context, string) (*database.Airdrop, []database.AirdropRow, error)
+	SendAirdrop(context.Context, string, int, bool) ([]database.AirdropRow, error)
+	ResolveAirdropRow(context.Context, string, string, int, bool, string) (*database.AirdropRow, error)
+	SendFunds(context.Context, string, codec.Address, uint64, string) (ids.ID, uint64, error)
 	Config() *config.Config
 }
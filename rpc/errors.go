@@ -0,0 +1,175 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+// ErrorCode is a stable numeric identifier of a faucet failure. Codes are
+// never reused once published.
+type ErrorCode int
+
+const (
+	// Challenge and payout failures
+	CodeInvalidAddress        ErrorCode = 1001
+	CodeSaltExpired           ErrorCode = 1002
+	CodeInvalidSolution       ErrorCode = 1003
+	CodeDuplicateSolution     ErrorCode = 1004
+	CodeMaintenance           ErrorCode = 1005
+	CodeNetworkFeeTooHigh     ErrorCode = 1006
+	CodeInsufficientFunds     ErrorCode = 1007
+	CodePayoutFailed          ErrorCode = 1008
+	CodeAddressDenied         ErrorCode = 1009
+	CodeInvalidOwnershipProof ErrorCode = 1010
+	CodePayoutUnconfirmed     ErrorCode = 1011
+	CodeInvalidArgument       ErrorCode = 1012
+
+	// Admin authentication failures
+	CodeUnauthorized    ErrorCode = 1101
+	CodeStaleRequest    ErrorCode = 1102
+	CodeReplayedRequest ErrorCode = 1103
+	CodeForbidden       ErrorCode = 1104
+
+	// Voucher failures
+	CodeVoucherNotFound  ErrorCode = 1201
+	CodeVoucherRevoked   ErrorCode = 1202
+	CodeVoucherExpired   ErrorCode = 1203
+	CodeVoucherExhausted ErrorCode = 1204
+	CodeVoucherRedeemed  ErrorCode = 1205
+
+	// API key failures
+	CodeInvalidAPIKey         ErrorCode = 1301
+	CodeAssetNotAllowed       ErrorCode = 1302
+	CodeDestinationNotAllowed ErrorCode = 1303
+	CodeQuotaExceeded         ErrorCode = 1304
+	CodeAmountNotAllowed      ErrorCode = 1305
+
+	// Admin payout failures
+	CodeAdminBudgetExceeded ErrorCode = 1401
+)
+
+var (
+	ErrInvalidAddress    = &Error{Code: CodeInvalidAddress, Message: "invalid address"}
+	ErrSaltExpired       = &Error{Code: CodeSaltExpired, Message: "salt expired", Retryable: true}
+	ErrInvalidSolution   = &Error{Code: CodeInvalidSolution, Message: "invalid solution"}
+	ErrDuplicateSolution = &Error{Code: CodeDuplicateSolution, Message: "duplicate solution"}
+	ErrMaintenance       = &Error{Code: CodeMaintenance, Message: "faucet is under maintenance", Retryable: true}
+	ErrNetworkFeeTooHigh = &Error{Code: CodeNetworkFeeTooHigh, Message: "network fee too high", Retryable: true}
+	ErrInsufficientFunds = &Error{Code: CodeInsufficientFunds, Message: "insufficient balance", Retryable: true}
+	ErrPayoutFailed      = &Error{Code: CodePayoutFailed, Message: "failed after retries", Retryable: true}
+	ErrAddressDenied     = &Error{Code: CodeAddressDenied, Message: "address is denied"}
+
+	ErrInvalidOwnershipProof = &Error{Code: CodeInvalidOwnershipProof, Message: "invalid proof of address ownership"}
+	ErrPayoutUnconfirmed     = &Error{Code: CodePayoutUnconfirmed, Message: "payout sent but not confirmed"}
+	ErrInvalidArgument       = &Error{Code: CodeInvalidArgument, Message: "invalid argument"}
+
+	ErrUnauthorized    = &Error{Code: CodeUnauthorized, Message: "unauthorized user"}
+	ErrStaleRequest    = &Error{Code: CodeStaleRequest, Message: "admin request timestamp outside allowed window"}
+	ErrReplayedRequest = &Error{Code: CodeReplayedRequest, Message: "admin request nonce already used"}
+	ErrForbidden       = &Error{Code: CodeForbidden, Message: "admin role not permitted to call method"}
+
+	ErrVoucherNotFound  = &Error{Code: CodeVoucherNotFound, Message: "voucher not found"}
+	ErrVoucherRevoked   = &Error{Code: CodeVoucherRevoked, Message: "voucher revoked"}
+	ErrVoucherExpired   = &Error{Code: CodeVoucherExpired, Message: "voucher expired"}
+	ErrVoucherExhausted = &Error{Code: CodeVoucherExhausted, Message: "voucher has no uses left"}
+	ErrVoucherRedeemed  = &Error{Code: CodeVoucherRedeemed, Message: "voucher already redeemed by this address"}
+
+	ErrInvalidAPIKey         = &Error{Code: CodeInvalidAPIKey, Message: "invalid or revoked API key"}
+	ErrAssetNotAllowed       = &Error{Code: CodeAssetNotAllowed, Message: "asset not allowed for API key"}
+	ErrDestinationNotAllowed = &Error{Code: CodeDestinationNotAllowed, Message: "destination not allowed for API key"}
+	ErrQuotaExceeded         = &Error{Code: CodeQuotaExceeded, Message: "API key daily quota exceeded", Retryable: true}
+	ErrAmountNotAllowed      = &Error{Code: CodeAmountNotAllowed, Message: "amount above the faucet amount"}
+
+	ErrAdminBudgetExceeded = &Error{Code: CodeAdminBudgetExceeded, Message: "admin payout budget exceeded"}
+)
+
+// Error is a faucet failure from the catalog above. It is sent to clients as
+// the data of the JSON-RPC error and decoded back by JSONRPCClient, so
+// errors.Is matches the catalog values on both sides.
+type Error struct {
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	Detail     string    `json:"detail,omitempty"`
+	Retryable  bool      `json:"retryable"`
+	RetryAfter int64     `json:"retryAfter,omitempty"` // seconds
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return e.Message
+	}
+	return e.Message + ": " + e.Detail
+}
+
+// Is matches any error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithDetail returns a copy of e with additional context
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+// WithRetryAfter returns a copy of e advising clients to wait d before
+// retrying
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = int64(d.Round(time.Second) / time.Second)
+	return &c
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Detail = err.Error()
+	c.cause = err
+	return &c
+}
+
+// toJSONRPCError converts catalog errors into JSON-RPC errors carrying the
+// catalog entry as data. Other errors are returned unchanged.
+func toJSONRPCError(err error) error {
+	var ferr *Error
+	if !errors.As(err, &ferr) {
+		return err
+	}
+	return &json2.Error{
+		Code:    json2.ErrorCode(ferr.Code),
+		Message: ferr.Error(),
+		Data:    ferr,
+	}
+}
+
+// fromJSONRPCError decodes a catalog error from the data of a JSON-RPC error.
+// Other errors are returned unchanged.
+func fromJSONRPCError(err error) error {
+	var jerr *json2.Error
+	if !errors.As(err, &jerr) || jerr.Data == nil {
+		return err
+	}
+	raw, merr := json.Marshal(jerr.Data)
+	if merr != nil {
+		return err
+	}
+	ferr := new(Error)
+	if json.Unmarshal(raw, ferr) != nil || ferr.Code == 0 {
+		return err
+	}
+	return ferr
+}
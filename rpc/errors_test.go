@@ -0,0 +1,119 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+var catalog = []*Error{
+	ErrInvalidAddress,
+	ErrSaltExpired,
+	ErrInvalidSolution,
+	ErrDuplicateSolution,
+	ErrMaintenance,
+	ErrNetworkFeeTooHigh,
+	ErrInsufficientFunds,
+	ErrPayoutFailed,
+	ErrAddressDenied,
+	ErrInvalidOwnershipProof,
+	ErrPayoutUnconfirmed,
+	ErrInvalidArgument,
+	ErrUnauthorized,
+	ErrStaleRequest,
+	ErrReplayedRequest,
+	ErrForbidden,
+	ErrVoucherNotFound,
+	ErrVoucherRevoked,
+	ErrVoucherExpired,
+	ErrVoucherExhausted,
+	ErrVoucherRedeemed,
+	ErrInvalidAPIKey,
+	ErrAssetNotAllowed,
+	ErrDestinationNotAllowed,
+	ErrQuotaExceeded,
+	ErrAmountNotAllowed,
+	ErrAdminBudgetExceeded,
+}
+
+// roundTrip sends err as the error of a JSON-RPC response through the json2
+// codec, the way the server does, and decodes it the way JSONRPCClient does
+func roundTrip(t *testing.T, err error) error {
+	body, merr := json2.EncodeClientRequest("faucet.test", struct{}{})
+	if merr != nil {
+		t.Fatal(merr)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	codecReq := json2.NewCodec().NewRequest(req)
+	if _, merr := codecReq.Method(); merr != nil {
+		t.Fatal(merr)
+	}
+	rec := httptest.NewRecorder()
+	codecReq.WriteError(rec, http.StatusOK, toJSONRPCError(err))
+
+	var reply struct{}
+	return fromJSONRPCError(json2.DecodeClientResponse(rec.Body, &reply))
+}
+
+func TestErrorRoundTrip(t *testing.T) {
+	codes := map[ErrorCode]bool{}
+	for _, sentinel := range catalog {
+		if codes[sentinel.Code] {
+			t.Fatalf("code %d is used twice", sentinel.Code)
+		}
+		codes[sentinel.Code] = true
+
+		sent := sentinel.WithDetail("some detail")
+		if sentinel.Retryable {
+			sent = sent.WithRetryAfter(90 * time.Second)
+		}
+		t.Run(fmt.Sprint(sentinel.Code), func(t *testing.T) {
+			err := roundTrip(t, fmt.Errorf("handler: %w", sent))
+			var got *Error
+			if !errors.As(err, &got) {
+				t.Fatalf("decoded %T %v, want *Error", err, err)
+			}
+			if got.Code != sent.Code || got.Message != sent.Message || got.Detail != sent.Detail ||
+				got.Retryable != sent.Retryable || got.RetryAfter != sent.RetryAfter {
+				t.Fatalf("decoded %+v, want %+v", got, sent)
+			}
+			if !errors.Is(err, sentinel) {
+				t.Fatalf("decoded error does not match %v", sentinel)
+			}
+		})
+	}
+}
+
+func TestErrorRoundTripWrapped(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := roundTrip(t, ErrPayoutFailed.Wrap(cause))
+	if !errors.Is(err, ErrPayoutFailed) {
+		t.Fatalf("decoded %v, want %v", err, ErrPayoutFailed)
+	}
+	var got *Error
+	if !errors.As(err, &got) || got.Detail != cause.Error() {
+		t.Fatalf("decoded %v, want detail %q", err, cause)
+	}
+}
+
+func TestErrorRoundTripUnknown(t *testing.T) {
+	err := roundTrip(t, errors.New("boom"))
+	var got *Error
+	if errors.As(err, &got) {
+		t.Fatalf("decoded catalog error %v from an unknown error", got)
+	}
+	var jerr *json2.Error
+	if !errors.As(err, &jerr) || jerr.Message != "boom" {
+		t.Fatalf("decoded %v, want the JSON-RPC error unchanged", err)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"strings"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/requester"
+	"github.com/nuklai/nuklai-faucet/database"
 )
 
 const (
@@ -31,7 +33,7 @@ func NewJSONRPCClient(uri string) *JSONRPCClient {
 
 func (cli *JSONRPCClient) FaucetAddress(ctx context.Context) (string, error) {
 	resp := new(FaucetAddressReply)
-	err := cli.requester.SendRequest(
+	err := cli.sendRequest(
 		ctx,
 		"faucetAddress",
 		nil,
@@ -40,43 +42,338 @@ func (cli *JSONRPCClient) FaucetAddress(ctx context.Context) (string, error) {
 	return resp.Address, err
 }
 
+// FaucetAddresses returns the address of every wallet the faucet pays out
+// from, starting with the primary one
+func (cli *JSONRPCClient) FaucetAddresses(ctx context.Context) ([]string, error) {
+	resp := new(FaucetAddressReply)
+	err := cli.sendRequest(
+		ctx,
+		"faucetAddress",
+		nil,
+		resp,
+	)
+	return resp.Addresses, err
+}
+
 func (cli *JSONRPCClient) Challenge(ctx context.Context) ([]byte, uint16, error) {
+	resp, err := cli.ChallengeInfo(ctx)
+	return resp.Salt, resp.Difficulty, err
+}
+
+// ChallengeInfo returns the full challenge reply, including the pause state
+func (cli *JSONRPCClient) ChallengeInfo(ctx context.Context) (*ChallengeReply, error) {
 	resp := new(ChallengeReply)
-	err := cli.requester.SendRequest(
+	err := cli.sendRequest(
 		ctx,
 		"challenge",
 		nil,
 		resp,
 	)
-	return resp.Salt, resp.Difficulty, err
+	return resp, err
 }
 
 func (cli *JSONRPCClient) SolveChallenge(ctx context.Context, addr string, salt []byte, solution []byte) (ids.ID, uint64, error) {
 	resp := new(SolveChallengeReply)
-	err := cli.requester.SendRequest(
+	err := cli.sendRequest(
+		ctx,
+		"solveChallenge",
+		&SolveChallengeArgs{
+			Address:  addr,
+			Salt:     salt,
+			Solution: solution,
+		},
+		resp,
+	)
+	return resp.TxID, resp.Amount, err
+}
+
+// SolveChallengeWithProof submits a solution along with the proof that the
+// submitter holds the key of addr, see SignOwnership
+func (cli *JSONRPCClient) SolveChallengeWithProof(ctx context.Context, addr string, salt []byte, solution []byte, proof *OwnershipProof) (ids.ID, uint64, error) {
+	resp := new(SolveChallengeReply)
+	err := cli.sendRequest(
 		ctx,
 		"solveChallenge",
 		&SolveChallengeArgs{
 			Address:  addr,
 			Salt:     salt,
 			Solution: solution,
+			Proof:    proof,
 		},
 		resp,
 	)
 	return resp.TxID, resp.Amount, err
 }
 
-// UpdateNuklaiRPC updates the RPC url for Nuklai, only if admin token is valid
-func (cli *JSONRPCClient) UpdateNuklaiRPC(ctx context.Context, adminToken string, newNuklaiRPCUrl string) (bool, error) {
+// UpdateNuklaiRPC updates the RPC url for Nuklai, only if signed by a
+// superadmin key
+func (cli *JSONRPCClient) UpdateNuklaiRPC(ctx context.Context, adminKey ed25519.PrivateKey, newNuklaiRPCUrl string) (bool, error) {
 	resp := new(UpdateNuklaiRPCReply)
-	err := cli.requester.SendRequest(
+	args := &UpdateNuklaiRPCArgs{
+		NuklaiRPCUrl: newNuklaiRPCUrl,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "updateNuklaiRPC", args, &args.Auth, resp)
+	return resp.Success, err
+}
+
+// Pause stops payouts until Resume is called, only if signed by an operator
+// key
+func (cli *JSONRPCClient) Pause(ctx context.Context, adminKey ed25519.PrivateKey, message string, resumeAt int64) (bool, error) {
+	resp := new(PauseReply)
+	args := &PauseArgs{
+		Message:  message,
+		ResumeAt: resumeAt,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "pause", args, &args.Auth, resp)
+	return resp.Success, err
+}
+
+// Resume re-enables payouts, only if signed by an operator key
+func (cli *JSONRPCClient) Resume(ctx context.Context, adminKey ed25519.PrivateKey) (bool, error) {
+	resp := new(ResumeReply)
+	args := &ResumeArgs{}
+	err := cli.sendAdminRequest(ctx, adminKey, "resume", args, &args.Auth, resp)
+	return resp.Success, err
+}
+
+// sendAdminRequest signs args with adminKey into auth and sends the request
+func (cli *JSONRPCClient) sendAdminRequest(ctx context.Context, adminKey ed25519.PrivateKey, method string, args any, auth *AdminAuth, reply any) error {
+	// The chain ID is fetched on every request so it follows RPC updates
+	challenge, err := cli.ChallengeInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := SignAdminRequest(adminKey, challenge.ChainID, method, args, auth); err != nil {
+		return err
+	}
+	return cli.sendRequest(ctx, method, args, reply)
+}
+
+// sendRequest sends the request and decodes faucet errors into *Error values
+// so callers can match them with errors.Is
+func (cli *JSONRPCClient) sendRequest(ctx context.Context, method string, args any, reply any, options ...requester.Option) error {
+	return fromJSONRPCError(cli.requester.SendRequest(ctx, method, args, reply, options...))
+}
+
+// Stats returns the faucet state and payout statistics, only if signed by a
+// viewer key
+func (cli *JSONRPCClient) Stats(ctx context.Context, adminKey ed25519.PrivateKey) (*StatsReply, error) {
+	resp := new(StatsReply)
+	args := &StatsArgs{}
+	err := cli.sendAdminRequest(ctx, adminKey, "stats", args, &args.Auth, resp)
+	return resp, err
+}
+
+// Transactions returns the most recent payouts, optionally filtered by
+// destination, only if signed by a viewer key
+func (cli *JSONRPCClient) Transactions(ctx context.Context, adminKey ed25519.PrivateKey, destination string, limit int) ([]database.Transaction, error) {
+	resp := new(TransactionsReply)
+	args := &TransactionsArgs{
+		Destination: destination,
+		Limit:       limit,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "transactions", args, &args.Auth, resp)
+	return resp.Transactions, err
+}
+
+// SearchTransactions returns the payouts matching filter, the most recent
+// first, only if signed by a viewer key
+func (cli *JSONRPCClient) SearchTransactions(ctx context.Context, adminKey ed25519.PrivateKey, filter database.TransactionFilter) ([]database.Transaction, error) {
+	resp := new(SearchTransactionsReply)
+	args := &SearchTransactionsArgs{TransactionFilter: filter}
+	err := cli.sendAdminRequest(ctx, adminKey, "searchTransactions", args, &args.Auth, resp)
+	return resp.Transactions, err
+}
+
+// DenyAddress refuses payouts to address, only if signed by an operator key
+func (cli *JSONRPCClient) DenyAddress(ctx context.Context, adminKey ed25519.PrivateKey, address string, reason string) (bool, error) {
+	resp := new(DenyAddressReply)
+	args := &DenyAddressArgs{
+		Address: address,
+		Reason:  reason,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "denyAddress", args, &args.Auth, resp)
+	return resp.Success, err
+}
+
+// RemoveDeniedAddress allows payouts to address again, only if signed by an
+// operator key
+func (cli *JSONRPCClient) RemoveDeniedAddress(ctx context.Context, adminKey ed25519.PrivateKey, address string) (bool, error) {
+	resp := new(RemoveDeniedAddressReply)
+	args := &RemoveDeniedAddressArgs{
+		Address: address,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "removeDeniedAddress", args, &args.Auth, resp)
+	return resp.Success, err
+}
+
+// DeniedAddresses returns the deny list, only if signed by a viewer key
+func (cli *JSONRPCClient) DeniedAddresses(ctx context.Context, adminKey ed25519.PrivateKey) ([]database.DeniedAddress, error) {
+	resp := new(DeniedAddressesReply)
+	args := &DeniedAddressesArgs{}
+	err := cli.sendAdminRequest(ctx, adminKey, "deniedAddresses", args, &args.Auth, resp)
+	return resp.Addresses, err
+}
+
+// RedeemVoucher pays the amount of the voucher code to addr without solving
+// a challenge. It returns the asset ID paid out, empty for the native asset.
+func (cli *JSONRPCClient) RedeemVoucher(ctx context.Context, addr string, code string) (ids.ID, uint64, string, error) {
+	resp := new(RedeemVoucherReply)
+	err := cli.sendRequest(
 		ctx,
-		"updateNuklaiRPC",
-		&UpdateNuklaiRPCArgs{
-			AdminToken:   adminToken,
-			NuklaiRPCUrl: newNuklaiRPCUrl,
+		"redeemVoucher",
+		&RedeemVoucherArgs{
+			Address: addr,
+			Code:    code,
 		},
 		resp,
 	)
+	return resp.TxID, resp.Amount, resp.Asset, err
+}
+
+// MintVouchers creates a batch of vouchers, only if signed by an operator
+// key. Zero values of args select the defaults.
+func (cli *JSONRPCClient) MintVouchers(ctx context.Context, adminKey ed25519.PrivateKey, args MintVouchersArgs) (*MintVouchersReply, error) {
+	resp := new(MintVouchersReply)
+	err := cli.sendAdminRequest(ctx, adminKey, "mintVouchers", &args, &args.Auth, resp)
+	return resp, err
+}
+
+// RevokeVouchers revokes the vouchers of batchID, or the voucher code if
+// batchID is empty, only if signed by an operator key
+func (cli *JSONRPCClient) RevokeVouchers(ctx context.Context, adminKey ed25519.PrivateKey, batchID string, code string) (int64, error) {
+	resp := new(RevokeVouchersReply)
+	args := &RevokeVouchersArgs{
+		BatchID: batchID,
+		Code:    code,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "revokeVouchers", args, &args.Auth, resp)
+	return resp.Revoked, err
+}
+
+// ExportVouchers returns the vouchers of batchID as CSV, only if signed by
+// an operator key
+func (cli *JSONRPCClient) ExportVouchers(ctx context.Context, adminKey ed25519.PrivateKey, batchID string) (string, error) {
+	resp := new(ExportVouchersReply)
+	args := &ExportVouchersArgs{
+		BatchID: batchID,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "exportVouchers", args, &args.Auth, resp)
+	return resp.CSV, err
+}
+
+// ClaimWithAPIKey pays amount of asset, an asset ID or NAI, to addr without
+// solving a challenge. Empty asset and zero amount select the defaults.
+func (cli *JSONRPCClient) ClaimWithAPIKey(ctx context.Context, apiKey string, addr string, asset string, amount uint64) (ids.ID, uint64, error) {
+	resp := new(ClaimWithAPIKeyReply)
+	err := cli.sendRequest(
+		ctx,
+		"claimWithAPIKey",
+		&ClaimWithAPIKeyArgs{
+			Address: addr,
+			Asset:   asset,
+			Amount:  amount,
+		},
+		resp,
+		requester.WithHeader("Authorization", "Bearer "+apiKey),
+	)
+	return resp.TxID, resp.Amount, err
+}
+
+// CreateAPIKey issues an API key, only if signed by a superadmin key. The
+// returned key cannot be retrieved again.
+func (cli *JSONRPCClient) CreateAPIKey(ctx context.Context, adminKey ed25519.PrivateKey, args CreateAPIKeyArgs) (*CreateAPIKeyReply, error) {
+	resp := new(CreateAPIKeyReply)
+	err := cli.sendAdminRequest(ctx, adminKey, "createAPIKey", &args, &args.Auth, resp)
+	return resp, err
+}
+
+// APIKeys returns every API key, only if signed by a viewer key
+func (cli *JSONRPCClient) APIKeys(ctx context.Context, adminKey ed25519.PrivateKey) ([]database.APIKey, error) {
+	resp := new(APIKeysReply)
+	args := &APIKeysArgs{}
+	err := cli.sendAdminRequest(ctx, adminKey, "apiKeys", args, &args.Auth, resp)
+	return resp.APIKeys, err
+}
+
+// RevokeAPIKey revokes the API key id, only if signed by an operator key
+func (cli *JSONRPCClient) RevokeAPIKey(ctx context.Context, adminKey ed25519.PrivateKey, id string) (bool, error) {
+	resp := new(RevokeAPIKeyReply)
+	args := &RevokeAPIKeyArgs{
+		ID: id,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "revokeAPIKey", args, &args.Auth, resp)
 	return resp.Success, err
 }
+
+// APIKeyUsage returns the daily usage of the API key id, or of every key if
+// empty, over the last days days, only if signed by a viewer key
+func (cli *JSONRPCClient) APIKeyUsage(ctx context.Context, adminKey ed25519.PrivateKey, id string, days int) ([]database.APIKeyUsage, error) {
+	resp := new(APIKeyUsageReply)
+	args := &APIKeyUsageArgs{
+		ID:   id,
+		Days: days,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "apiKeyUsage", args, &args.Auth, resp)
+	return resp.Usage, err
+}
+
+// CreateAirdrop saves an airdrop to recipients, only if signed by a
+// superadmin key. Nothing is sent until SendAirdrop is called.
+func (cli *JSONRPCClient) CreateAirdrop(ctx context.Context, adminKey ed25519.PrivateKey, args CreateAirdropArgs) (*CreateAirdropReply, error) {
+	resp := new(CreateAirdropReply)
+	err := cli.sendAdminRequest(ctx, adminKey, "createAirdrop", &args, &args.Auth, resp)
+	return resp, err
+}
+
+// SendAirdrop sends the next batch of the airdrop id, only if signed by an
+// operator key. retryFailed puts the failed rows back to pending first.
+func (cli *JSONRPCClient) SendAirdrop(ctx context.Context, adminKey ed25519.PrivateKey, id string, batchSize int, retryFailed bool) (*SendAirdropReply, error) {
+	resp := new(SendAirdropReply)
+	args := &SendAirdropArgs{
+		ID:          id,
+		BatchSize:   batchSize,
+		RetryFailed: retryFailed,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "sendAirdrop", args, &args.Auth, resp)
+	return resp, err
+}
+
+// AirdropReport returns the airdrop id with its rows with status, or every
+// row if empty, only if signed by a viewer key
+func (cli *JSONRPCClient) AirdropReport(ctx context.Context, adminKey ed25519.PrivateKey, id string, status string) (*AirdropReportReply, error) {
+	resp := new(AirdropReportReply)
+	args := &AirdropReportArgs{
+		ID:     id,
+		Status: status,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "airdropReport", args, &args.Auth, resp)
+	return resp, err
+}
+
+// ResolveAirdropRow settles the unconfirmed row line of the airdrop id as
+// sent, with txID if set, or as failed, only if signed by an operator key
+func (cli *JSONRPCClient) ResolveAirdropRow(ctx context.Context, adminKey ed25519.PrivateKey, id string, line int, sent bool, txID string) (*database.AirdropRow, error) {
+	resp := new(ResolveAirdropRowReply)
+	args := &ResolveAirdropRowArgs{
+		ID:   id,
+		Line: line,
+		Sent: sent,
+		TxID: txID,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "resolveAirdropRow", args, &args.Auth, resp)
+	return &resp.Row, err
+}
+
+// SendFunds pays amount, or the configured amount if zero, to addr for
+// reason, only if signed by an operator key
+func (cli *JSONRPCClient) SendFunds(ctx context.Context, adminKey ed25519.PrivateKey, addr string, amount uint64, reason string) (ids.ID, uint64, error) {
+	resp := new(SendFundsReply)
+	args := &SendFundsArgs{
+		Address: addr,
+		Amount:  amount,
+		Reason:  reason,
+	}
+	err := cli.sendAdminRequest(ctx, adminKey, "sendFunds", args, &args.Auth, resp)
+	return resp.TxID, resp.Amount, err
+}
@@ -4,47 +4,96 @@
 package rpc
 
 import (
-	"errors"
+	"context"
+	"encoding/csv"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/trace"
 	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-faucet/config"
+	"github.com/nuklai/nuklai-faucet/database"
 	"github.com/nuklai/nuklaivm/consts"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type JSONRPCServer struct {
-	m Manager
+	m       Manager
+	admin   *adminAuthenticator
+	metrics *metrics
+	tracer  trace.Tracer
 }
 
-func NewJSONRPCServer(m Manager) *JSONRPCServer {
-	return &JSONRPCServer{m}
+func NewJSONRPCServer(m Manager, registry prometheus.Registerer, tracer trace.Tracer) (*JSONRPCServer, error) {
+	metrics, err := newMetrics(registry)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONRPCServer{m: m, admin: newAdminAuthenticator(func() []config.AdminKey { return m.Config().AdminKeys }, m.ChainID), metrics: metrics, tracer: tracer}, nil
 }
 
 type FaucetAddressReply struct {
-	Address string `json:"address"`
+	Address   string   `json:"address"`   // primary wallet
+	Addresses []string `json:"addresses"` // every wallet payouts are sent from
 }
 
 func (j *JSONRPCServer) FaucetAddress(req *http.Request, _ *struct{}, reply *FaucetAddressReply) (err error) {
 	addr, err := j.m.GetFaucetAddress(req.Context())
 	if err != nil {
-		return err
+		return toJSONRPCError(err)
+	}
+	addrs, err := j.m.GetFaucetAddresses(req.Context())
+	if err != nil {
+		return toJSONRPCError(err)
 	}
 	reply.Address = codec.MustAddressBech32(consts.HRP, addr)
+	for _, addr := range addrs {
+		reply.Addresses = append(reply.Addresses, codec.MustAddressBech32(consts.HRP, addr))
+	}
 	return nil
 }
 
 type ChallengeReply struct {
 	Salt       []byte `json:"salt"`
 	Difficulty uint16 `json:"difficulty"`
+	Paused     bool   `json:"paused"`
+	Message    string `json:"message,omitempty"`
+	ResumeAt   int64  `json:"resumeAt,omitempty"`
+	// Nonce is signed along with the salt and solution by ownership proofs
+	Nonce []byte `json:"nonce"`
+	// RequireOwnershipProof is set when solutions must carry a proof
+	RequireOwnershipProof bool `json:"requireOwnershipProof"`
+	// ChainID is the chain payouts are sent on, signed by admin requests
+	ChainID ids.ID `json:"chainID"`
 }
 
 func (j *JSONRPCServer) Challenge(req *http.Request, _ *struct{}, reply *ChallengeReply) (err error) {
-	salt, difficulty, err := j.m.GetChallenge(req.Context())
+	ctx, span := j.tracer.Start(req.Context(), "JSONRPCServer.Challenge")
+	defer span.End()
+
+	salt, difficulty, err := j.m.GetChallenge(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	paused, message, resumeAt, err := j.m.GetPauseState(ctx)
 	if err != nil {
-		return err
+		return toJSONRPCError(err)
 	}
+	j.metrics.challengesServed.Inc()
 	reply.Salt = salt
 	reply.Difficulty = difficulty
+	reply.Nonce = j.m.ChallengeNonce(salt)
+	reply.RequireOwnershipProof = j.m.Config().RequireOwnershipProof
+	reply.ChainID = j.m.ChainID()
+	reply.Paused = paused
+	reply.Message = message
+	reply.ResumeAt = resumeAt
 	return nil
 }
 
@@ -52,6 +101,9 @@ type SolveChallengeArgs struct {
 	Address  string `json:"address"`
 	Salt     []byte `json:"salt"`
 	Solution []byte `json:"solution"`
+	// Proof proves that the submitter holds the key of Address, optional
+	// unless the faucet requires it
+	Proof *OwnershipProof `json:"proof,omitempty"`
 }
 
 type SolveChallengeReply struct {
@@ -60,22 +112,34 @@ type SolveChallengeReply struct {
 }
 
 func (j *JSONRPCServer) SolveChallenge(req *http.Request, args *SolveChallengeArgs, reply *SolveChallengeReply) error {
-	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
-	if err != nil {
-		return err
-	}
-	txID, amount, err := j.m.SolveChallenge(req.Context(), addr, args.Salt, args.Solution)
+	ctx, span := j.tracer.Start(req.Context(), "JSONRPCServer.SolveChallenge",
+		oteltrace.WithAttributes(attribute.String("address", args.Address)),
+	)
+	defer span.End()
+
+	ctx = WithClientInfo(ctx, newClientInfo(req, j.m.Config().TrustForwardedFor))
+	txID, amount, err := j.solveChallenge(ctx, args)
+	j.metrics.observeSolution(err)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		return toJSONRPCError(err)
 	}
 	reply.TxID = txID
 	reply.Amount = amount
 	return nil
 }
 
+func (j *JSONRPCServer) solveChallenge(ctx context.Context, args *SolveChallengeArgs) (ids.ID, uint64, error) {
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return ids.Empty, 0, ErrInvalidAddress.Wrap(err)
+	}
+	return j.m.SolveChallenge(ctx, addr, args.Salt, args.Solution, args.Proof)
+}
+
 type UpdateNuklaiRPCArgs struct {
-	AdminToken   string `json:"adminToken"`
-	NuklaiRPCUrl string `json:"nuklaiRPCUrl"`
+	Auth         AdminAuth `json:"auth"`
+	NuklaiRPCUrl string    `json:"nuklaiRPCUrl"`
 }
 
 type UpdateNuklaiRPCReply struct {
@@ -83,14 +147,684 @@ type UpdateNuklaiRPCReply struct {
 }
 
 func (j *JSONRPCServer) UpdateNuklaiRPC(req *http.Request, args *UpdateNuklaiRPCArgs, reply *UpdateNuklaiRPCReply) error {
-	// Validate the admin token
-	if args.AdminToken != j.m.Config().AdminToken {
-		return errors.New("unauthorized user")
+	if _, err := j.admin.authorize("updateNuklaiRPC", args, &args.Auth, config.RoleSuperAdmin); err != nil {
+		return toJSONRPCError(err)
 	}
 	err := j.m.UpdateNuklaiRPC(req.Context(), args.NuklaiRPCUrl)
 	if err != nil {
-		return err
+		return toJSONRPCError(err)
 	}
 	reply.Success = true
 	return nil
 }
+
+type PauseArgs struct {
+	Auth     AdminAuth `json:"auth"`
+	Message  string    `json:"message"`
+	ResumeAt int64     `json:"resumeAt"` // unix seconds, 0 if unknown
+}
+
+type PauseReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) Pause(req *http.Request, args *PauseArgs, reply *PauseReply) error {
+	if _, err := j.admin.authorize("pause", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	if err := j.m.Pause(req.Context(), args.Message, args.ResumeAt); err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Success = true
+	return nil
+}
+
+type ResumeArgs struct {
+	Auth AdminAuth `json:"auth"`
+}
+
+type ResumeReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) Resume(req *http.Request, args *ResumeArgs, reply *ResumeReply) error {
+	if _, err := j.admin.authorize("resume", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	if err := j.m.Resume(req.Context()); err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Success = true
+	return nil
+}
+
+type StatsArgs struct {
+	Auth AdminAuth `json:"auth"`
+}
+
+type StatsReply struct {
+	Network      string               `json:"network,omitempty"`
+	ChainID      string               `json:"chainID"`
+	Address      string               `json:"address"`
+	Balance      uint64               `json:"balance"`
+	Difficulty   uint16               `json:"difficulty"`
+	Paused       bool                 `json:"paused"`
+	Transactions database.Stats       `json:"transactions"`
+	Refills      database.RefillStats `json:"refills"`
+}
+
+func (j *JSONRPCServer) Stats(req *http.Request, args *StatsArgs, reply *StatsReply) error {
+	if _, err := j.admin.authorize("stats", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	ctx := req.Context()
+	addr, err := j.m.GetFaucetAddress(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	balance, err := j.m.GetBalance(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	_, difficulty, err := j.m.GetChallenge(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	paused, _, _, err := j.m.GetPauseState(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	stats, err := j.m.GetTransactionStats(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	refills, err := j.m.GetRefillStats(ctx)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Network = j.m.Config().Network
+	reply.ChainID = j.m.ChainID().String()
+	reply.Address = codec.MustAddressBech32(consts.HRP, addr)
+	reply.Balance = balance
+	reply.Difficulty = difficulty
+	reply.Paused = paused
+	reply.Transactions = *stats
+	reply.Refills = *refills
+	return nil
+}
+
+type SearchTransactionsArgs struct {
+	Auth AdminAuth `json:"auth"`
+	database.TransactionFilter
+}
+
+type SearchTransactionsReply struct {
+	Transactions []database.Transaction `json:"transactions"`
+}
+
+func (j *JSONRPCServer) SearchTransactions(req *http.Request, args *SearchTransactionsArgs, reply *SearchTransactionsReply) error {
+	if _, err := j.admin.authorize("searchTransactions", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	txs, err := j.m.SearchTransactions(req.Context(), &args.TransactionFilter)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Transactions = txs
+	return nil
+}
+
+type TransactionsArgs struct {
+	Auth        AdminAuth `json:"auth"`
+	Destination string    `json:"destination"` // optional
+	Limit       int       `json:"limit"`       // 0 for no limit
+}
+
+type TransactionsReply struct {
+	Transactions []database.Transaction `json:"transactions"`
+}
+
+func (j *JSONRPCServer) Transactions(req *http.Request, args *TransactionsArgs, reply *TransactionsReply) error {
+	if _, err := j.admin.authorize("transactions", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	txs, err := j.m.GetTransactions(req.Context(), args.Destination, args.Limit)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Transactions = txs
+	return nil
+}
+
+type DenyAddressArgs struct {
+	Auth    AdminAuth `json:"auth"`
+	Address string    `json:"address"`
+	Reason  string    `json:"reason"`
+}
+
+type DenyAddressReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) DenyAddress(req *http.Request, args *DenyAddressArgs, reply *DenyAddressReply) error {
+	if _, err := j.admin.authorize("denyAddress", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return toJSONRPCError(ErrInvalidAddress.Wrap(err))
+	}
+	if err := j.m.DenyAddress(req.Context(), addr, args.Reason); err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Success = true
+	return nil
+}
+
+type RemoveDeniedAddressArgs struct {
+	Auth    AdminAuth `json:"auth"`
+	Address string    `json:"address"`
+}
+
+type RemoveDeniedAddressReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) RemoveDeniedAddress(req *http.Request, args *RemoveDeniedAddressArgs, reply *RemoveDeniedAddressReply) error {
+	if _, err := j.admin.authorize("removeDeniedAddress", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return toJSONRPCError(ErrInvalidAddress.Wrap(err))
+	}
+	if err := j.m.RemoveDeniedAddress(req.Context(), addr); err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Success = true
+	return nil
+}
+
+type DeniedAddressesArgs struct {
+	Auth AdminAuth `json:"auth"`
+}
+
+type DeniedAddressesReply struct {
+	Addresses []database.DeniedAddress `json:"addresses"`
+}
+
+func (j *JSONRPCServer) DeniedAddresses(req *http.Request, args *DeniedAddressesArgs, reply *DeniedAddressesReply) error {
+	if _, err := j.admin.authorize("deniedAddresses", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	denied, err := j.m.GetDeniedAddresses(req.Context())
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Addresses = denied
+	return nil
+}
+
+type RedeemVoucherArgs struct {
+	Address string `json:"address"`
+	Code    string `json:"code"`
+}
+
+type RedeemVoucherReply struct {
+	TxID   ids.ID `json:"txID"`
+	Amount uint64 `json:"amount"`
+	Asset  string `json:"asset,omitempty"` // asset ID, empty for the native asset
+}
+
+func (j *JSONRPCServer) RedeemVoucher(req *http.Request, args *RedeemVoucherArgs, reply *RedeemVoucherReply) error {
+	ctx, span := j.tracer.Start(req.Context(), "JSONRPCServer.RedeemVoucher",
+		oteltrace.WithAttributes(attribute.String("address", args.Address)),
+	)
+	defer span.End()
+
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return toJSONRPCError(ErrInvalidAddress.Wrap(err))
+	}
+	ctx = WithClientInfo(ctx, newClientInfo(req, j.m.Config().TrustForwardedFor))
+	txID, voucher, err := j.m.RedeemVoucher(ctx, addr, args.Code)
+	if err != nil {
+		span.RecordError(err)
+		return toJSONRPCError(err)
+	}
+	reply.TxID = txID
+	reply.Amount = voucher.Amount
+	reply.Asset = voucher.Asset
+	return nil
+}
+
+type MintVouchersArgs struct {
+	Auth      AdminAuth `json:"auth"`
+	Count     int       `json:"count"`
+	Amount    uint64    `json:"amount"`    // 0 for the configured amount
+	Asset     string    `json:"asset"`     // asset ID, empty for the native asset
+	MaxUses   uint32    `json:"maxUses"`   // 0 for a single use
+	ExpiresAt int64     `json:"expiresAt"` // unix seconds, 0 for never
+}
+
+type MintVouchersReply struct {
+	BatchID  string             `json:"batchID"`
+	Vouchers []database.Voucher `json:"vouchers"`
+}
+
+func (j *JSONRPCServer) MintVouchers(req *http.Request, args *MintVouchersArgs, reply *MintVouchersReply) error {
+	key, err := j.admin.authorize("mintVouchers", args, &args.Auth, config.RoleOperator)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	asset := ids.Empty
+	if args.Asset != "" {
+		if asset, err = ids.FromString(args.Asset); err != nil {
+			return toJSONRPCError(ErrInvalidArgument.Wrap(fmt.Errorf("invalid asset ID: %w", err)))
+		}
+	}
+	batchID, vouchers, err := j.m.MintVouchers(req.Context(), key.Name, args.Count, args.Amount, asset, args.MaxUses, args.ExpiresAt)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.BatchID = batchID
+	reply.Vouchers = vouchers
+	return nil
+}
+
+type RevokeVouchersArgs struct {
+	Auth    AdminAuth `json:"auth"`
+	BatchID string    `json:"batchID"` // every voucher of the batch if set
+	Code    string    `json:"code"`    // a single voucher otherwise
+}
+
+type RevokeVouchersReply struct {
+	Revoked int64 `json:"revoked"`
+}
+
+func (j *JSONRPCServer) RevokeVouchers(req *http.Request, args *RevokeVouchersArgs, reply *RevokeVouchersReply) error {
+	if _, err := j.admin.authorize("revokeVouchers", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	revoked, err := j.m.RevokeVouchers(req.Context(), args.BatchID, args.Code)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Revoked = revoked
+	return nil
+}
+
+type ExportVouchersArgs struct {
+	Auth    AdminAuth `json:"auth"`
+	BatchID string    `json:"batchID"`
+}
+
+type ExportVouchersReply struct {
+	CSV string `json:"csv"`
+}
+
+// voucherCSVHeader names the columns of exported vouchers
+var voucherCSVHeader = []string{"code", "batch_id", "chain_id", "asset", "amount", "max_uses", "uses", "expires_at", "revoked_at", "created_by", "created_at"}
+
+func (j *JSONRPCServer) ExportVouchers(req *http.Request, args *ExportVouchersArgs, reply *ExportVouchersReply) error {
+	if _, err := j.admin.authorize("exportVouchers", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	vouchers, err := j.m.GetVouchers(req.Context(), args.BatchID)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(voucherCSVHeader); err != nil {
+		return toJSONRPCError(err)
+	}
+	for _, v := range vouchers {
+		record := []string{
+			v.Code, v.BatchID, v.ChainID, v.Asset,
+			strconv.FormatUint(v.Amount, 10),
+			strconv.FormatUint(uint64(v.MaxUses), 10),
+			strconv.FormatUint(uint64(v.Uses), 10),
+			strconv.FormatInt(v.ExpiresAt, 10),
+			strconv.FormatInt(v.RevokedAt, 10),
+			v.CreatedBy,
+			strconv.FormatInt(v.CreatedAt, 10),
+		}
+		if err := w.Write(record); err != nil {
+			return toJSONRPCError(err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.CSV = b.String()
+	return nil
+}
+
+// apiKeyFromRequest returns the key of an "Authorization: Bearer <key>"
+// header, or an empty string
+func apiKeyFromRequest(req *http.Request) string {
+	scheme, key, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+type ClaimWithAPIKeyArgs struct {
+	Address string `json:"address"`
+	Asset   string `json:"asset"`  // asset ID or NAI, empty for the native asset
+	Amount  uint64 `json:"amount"` // 0 for the configured amount
+}
+
+type ClaimWithAPIKeyReply struct {
+	TxID   ids.ID `json:"txID"`
+	Amount uint64 `json:"amount"`
+}
+
+// ClaimWithAPIKey pays out without a challenge to clients authenticated with
+// an API key in the Authorization header
+func (j *JSONRPCServer) ClaimWithAPIKey(req *http.Request, args *ClaimWithAPIKeyArgs, reply *ClaimWithAPIKeyReply) error {
+	ctx, span := j.tracer.Start(req.Context(), "JSONRPCServer.ClaimWithAPIKey",
+		oteltrace.WithAttributes(attribute.String("address", args.Address)),
+	)
+	defer span.End()
+
+	key := apiKeyFromRequest(req)
+	if key == "" {
+		return toJSONRPCError(ErrInvalidAPIKey.WithDetail("missing Authorization: Bearer header"))
+	}
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return toJSONRPCError(ErrInvalidAddress.Wrap(err))
+	}
+	asset := ids.Empty
+	if args.Asset != "" && !strings.EqualFold(args.Asset, consts.Symbol) {
+		if asset, err = ids.FromString(args.Asset); err != nil {
+			return toJSONRPCError(ErrInvalidArgument.Wrap(fmt.Errorf("invalid asset ID: %w", err)))
+		}
+	}
+	ctx = WithClientInfo(ctx, newClientInfo(req, j.m.Config().TrustForwardedFor))
+	txID, amount, err := j.m.ClaimWithAPIKey(ctx, key, addr, asset, args.Amount)
+	if err != nil {
+		span.RecordError(err)
+		return toJSONRPCError(err)
+	}
+	reply.TxID = txID
+	reply.Amount = amount
+	return nil
+}
+
+type CreateAPIKeyArgs struct {
+	Auth                AdminAuth `json:"auth"`
+	Name                string    `json:"name"`
+	DailyAmount         uint64    `json:"dailyAmount"`   // 0 for no limit
+	DailyRequests       uint32    `json:"dailyRequests"` // 0 for no limit
+	AllowedAssets       []string  `json:"allowedAssets"`
+	AllowedDestinations []string  `json:"allowedDestinations"`
+}
+
+type CreateAPIKeyReply struct {
+	// Key is only returned once, the faucet keeps a hash of it
+	Key    string          `json:"key"`
+	APIKey database.APIKey `json:"apiKey"`
+}
+
+func (j *JSONRPCServer) CreateAPIKey(req *http.Request, args *CreateAPIKeyArgs, reply *CreateAPIKeyReply) error {
+	admin, err := j.admin.authorize("createAPIKey", args, &args.Auth, config.RoleSuperAdmin)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	key, apiKey, err := j.m.CreateAPIKey(req.Context(), admin.Name, args.Name, args.DailyAmount, args.DailyRequests, args.AllowedAssets, args.AllowedDestinations)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Key = key
+	reply.APIKey = *apiKey
+	return nil
+}
+
+type APIKeysArgs struct {
+	Auth AdminAuth `json:"auth"`
+}
+
+type APIKeysReply struct {
+	APIKeys []database.APIKey `json:"apiKeys"`
+}
+
+func (j *JSONRPCServer) APIKeys(req *http.Request, args *APIKeysArgs, reply *APIKeysReply) error {
+	if _, err := j.admin.authorize("apiKeys", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	keys, err := j.m.GetAPIKeys(req.Context())
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.APIKeys = keys
+	return nil
+}
+
+type RevokeAPIKeyArgs struct {
+	Auth AdminAuth `json:"auth"`
+	ID   string    `json:"id"`
+}
+
+type RevokeAPIKeyReply struct {
+	Success bool `json:"success"`
+}
+
+func (j *JSONRPCServer) RevokeAPIKey(req *http.Request, args *RevokeAPIKeyArgs, reply *RevokeAPIKeyReply) error {
+	if _, err := j.admin.authorize("revokeAPIKey", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	revoked, err := j.m.RevokeAPIKey(req.Context(), args.ID)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Success = revoked
+	return nil
+}
+
+type APIKeyUsageArgs struct {
+	Auth AdminAuth `json:"auth"`
+	ID   string    `json:"id"`   // every key if empty
+	Days int       `json:"days"` // days including today, 1 if 0
+}
+
+type APIKeyUsageReply struct {
+	Usage []database.APIKeyUsage `json:"usage"`
+}
+
+func (j *JSONRPCServer) APIKeyUsage(req *http.Request, args *APIKeyUsageArgs, reply *APIKeyUsageReply) error {
+	if _, err := j.admin.authorize("apiKeyUsage", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	usage, err := j.m.GetAPIKeyUsage(req.Context(), args.ID, args.Days)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Usage = usage
+	return nil
+}
+
+type CreateAirdropArgs struct {
+	Auth       AdminAuth          `json:"auth"`
+	Name       string             `json:"name"`
+	Asset      string             `json:"asset"` // asset ID, empty for the native asset
+	Recipients []AirdropRecipient `json:"recipients"`
+	// CSV lists the recipients as address,amount records instead, see
+	// ParseAirdropCSV
+	CSV string `json:"csv,omitempty"`
+}
+
+type CreateAirdropReply struct {
+	Airdrop database.Airdrop        `json:"airdrop"`
+	Summary database.AirdropSummary `json:"summary"`
+}
+
+func (j *JSONRPCServer) CreateAirdrop(req *http.Request, args *CreateAirdropArgs, reply *CreateAirdropReply) error {
+	admin, err := j.admin.authorize("createAirdrop", args, &args.Auth, config.RoleSuperAdmin)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	asset := ids.Empty
+	if args.Asset != "" {
+		if asset, err = ids.FromString(args.Asset); err != nil {
+			return toJSONRPCError(ErrInvalidArgument.Wrap(fmt.Errorf("invalid asset ID: %w", err)))
+		}
+	}
+	recipients := args.Recipients
+	if args.CSV != "" {
+		if len(recipients) > 0 {
+			return toJSONRPCError(ErrInvalidArgument.WithDetail("recipients and csv are mutually exclusive"))
+		}
+		if recipients, err = ParseAirdropCSV(args.CSV); err != nil {
+			return toJSONRPCError(ErrInvalidArgument.Wrap(fmt.Errorf("invalid airdrop CSV: %w", err)))
+		}
+	}
+	airdrop, rows, err := j.m.CreateAirdrop(req.Context(), admin.Name, args.Name, asset, recipients)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Airdrop = *airdrop
+	reply.Summary = database.SummarizeAirdrop(rows)
+	return nil
+}
+
+type SendAirdropArgs struct {
+	Auth        AdminAuth `json:"auth"`
+	ID          string    `json:"id"`
+	BatchSize   int       `json:"batchSize"`   // rows sent concurrently, 0 for the default
+	RetryFailed bool      `json:"retryFailed"` // put the failed rows back to pending first
+}
+
+type SendAirdropReply struct {
+	// Rows are the rows of the batch, sent or failed
+	Rows    []database.AirdropRow   `json:"rows"`
+	Summary database.AirdropSummary `json:"summary"` // of the whole airdrop
+}
+
+// SendAirdrop sends the next batch of an airdrop. Clients call it until no
+// row is pending.
+func (j *JSONRPCServer) SendAirdrop(req *http.Request, args *SendAirdropArgs, reply *SendAirdropReply) error {
+	if _, err := j.admin.authorize("sendAirdrop", args, &args.Auth, config.RoleOperator); err != nil {
+		return toJSONRPCError(err)
+	}
+	ctx := req.Context()
+	rows, err := j.m.SendAirdrop(ctx, args.ID, args.BatchSize, args.RetryFailed)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	_, all, err := j.m.GetAirdrop(ctx, args.ID)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Rows = rows
+	reply.Summary = database.SummarizeAirdrop(all)
+	return nil
+}
+
+type AirdropReportArgs struct {
+	Auth   AdminAuth `json:"auth"`
+	ID     string    `json:"id"`
+	Status string    `json:"status"` // only the rows with this status if set
+}
+
+type AirdropReportReply struct {
+	Airdrop database.Airdrop        `json:"airdrop"`
+	Summary database.AirdropSummary `json:"summary"`
+	Rows    []database.AirdropRow   `json:"rows"`
+}
+
+func (j *JSONRPCServer) AirdropReport(req *http.Request, args *AirdropReportArgs, reply *AirdropReportReply) error {
+	if _, err := j.admin.authorize("airdropReport", args, &args.Auth, config.RoleViewer); err != nil {
+		return toJSONRPCError(err)
+	}
+	airdrop, rows, err := j.m.GetAirdrop(req.Context(), args.ID)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Airdrop = *airdrop
+	reply.Summary = database.SummarizeAirdrop(rows)
+	for _, row := range rows {
+		if args.Status == "" || row.Status == args.Status {
+			reply.Rows = append(reply.Rows, row)
+		}
+	}
+	return nil
+}
+
+type ResolveAirdropRowArgs struct {
+	Auth AdminAuth `json:"auth"`
+	ID   string    `json:"id"`
+	Line int       `json:"line"`
+	// Sent resolves the row as sent, otherwise as failed
+	Sent bool   `json:"sent"`
+	TxID string `json:"txID,omitempty"` // transaction of a sent row, if not recorded
+}
+
+type ResolveAirdropRowReply struct {
+	Row database.AirdropRow `json:"row"`
+}
+
+// ResolveAirdropRow settles an unconfirmed airdrop row once its transfer was
+// checked on chain
+func (j *JSONRPCServer) ResolveAirdropRow(req *http.Request, args *ResolveAirdropRowArgs, reply *ResolveAirdropRowReply) error {
+	admin, err := j.admin.authorize("resolveAirdropRow", args, &args.Auth, config.RoleOperator)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	if args.TxID != "" {
+		if !args.Sent {
+			return toJSONRPCError(ErrInvalidArgument.WithDetail("a transaction ID is only set on sent rows"))
+		}
+		if _, err := ids.FromString(args.TxID); err != nil {
+			return toJSONRPCError(ErrInvalidArgument.Wrap(fmt.Errorf("invalid transaction ID: %w", err)))
+		}
+	}
+	row, err := j.m.ResolveAirdropRow(req.Context(), admin.Name, args.ID, args.Line, args.Sent, args.TxID)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Row = *row
+	return nil
+}
+
+type SendFundsArgs struct {
+	Auth    AdminAuth `json:"auth"`
+	Address string    `json:"address"`
+	Amount  uint64    `json:"amount"` // 0 for the configured amount
+	Reason  string    `json:"reason"`
+}
+
+type SendFundsReply struct {
+	TxID   ids.ID `json:"txID"`
+	Amount uint64 `json:"amount"`
+}
+
+// SendFunds pays an address on behalf of an admin, within the admin budget
+func (j *JSONRPCServer) SendFunds(req *http.Request, args *SendFundsArgs, reply *SendFundsReply) error {
+	admin, err := j.admin.authorize("sendFunds", args, &args.Auth, config.RoleOperator)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	ctx, span := j.tracer.Start(req.Context(), "JSONRPCServer.SendFunds",
+		oteltrace.WithAttributes(attribute.String("address", args.Address), attribute.String("admin", admin.Name)),
+	)
+	defer span.End()
+
+	addr, err := codec.ParseAddressBech32(consts.HRP, args.Address)
+	if err != nil {
+		return toJSONRPCError(ErrInvalidAddress.Wrap(err))
+	}
+	ctx = WithClientInfo(ctx, newClientInfo(req, j.m.Config().TrustForwardedFor))
+	txID, amount, err := j.m.SendFunds(ctx, admin.Name, addr, args.Amount, args.Reason)
+	if err != nil {
+		span.RecordError(err)
+		return toJSONRPCError(err)
+	}
+	reply.TxID = txID
+	reply.Amount = amount
+	return nil
+}
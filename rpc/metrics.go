@@ -0,0 +1,77 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"errors"
+
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "faucet"
+
+// rejectionReasons labels rejected solutions by error code
+var rejectionReasons = map[ErrorCode]string{
+	CodeInvalidAddress:    "invalid_address",
+	CodeSaltExpired:       "salt_expired",
+	CodeInvalidSolution:   "invalid_solution",
+	CodeDuplicateSolution: "duplicate_solution",
+	CodeMaintenance:       "maintenance",
+	CodeNetworkFeeTooHigh: "network_fee_too_high",
+	CodeInsufficientFunds: "insufficient_funds",
+	CodePayoutFailed:      "payout_failed",
+	CodeAddressDenied:     "address_denied",
+
+	CodeInvalidOwnershipProof: "invalid_ownership_proof",
+}
+
+type metrics struct {
+	challengesServed  prometheus.Counter
+	solutionsAccepted prometheus.Counter
+	solutionsRejected *prometheus.CounterVec
+}
+
+func newMetrics(r prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		challengesServed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "challenges_served_total",
+			Help:      "number of challenges served",
+		}),
+		solutionsAccepted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "solutions_accepted_total",
+			Help:      "number of solutions that were paid out",
+		}),
+		solutionsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "solutions_rejected_total",
+			Help:      "number of rejected solutions by reason",
+		}, []string{"reason"}),
+	}
+	errs := wrappers.Errs{}
+	errs.Add(
+		r.Register(m.challengesServed),
+		r.Register(m.solutionsAccepted),
+		r.Register(m.solutionsRejected),
+	)
+	return m, errs.Err
+}
+
+// observeSolution records the result of a solveChallenge call
+func (m *metrics) observeSolution(err error) {
+	if err == nil {
+		m.solutionsAccepted.Inc()
+		return
+	}
+	reason := "other"
+	var ferr *Error
+	if errors.As(err, &ferr) {
+		if r, ok := rejectionReasons[ferr.Code]; ok {
+			reason = r
+		}
+	}
+	m.solutionsRejected.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklaivm/auth"
+)
+
+// ownershipDomain separates ownership proofs from any other message signed
+// by the same key
+const ownershipDomain = "nuklai-faucet ownership"
+
+// OwnershipProof proves that the submitter of a solution holds the key of the
+// destination address. The signature is made with that key over the payload
+// returned by OwnershipPayload.
+type OwnershipProof struct {
+	PublicKey []byte `json:"publicKey"`
+	Signature []byte `json:"signature"`
+}
+
+// OwnershipPayload returns the bytes signed by the key of the destination
+// address. It covers the salt, the solution and the nonce of the challenge,
+// encoded in base64 and separated by newlines after a fixed domain line.
+func OwnershipPayload(salt, solution, nonce []byte) []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%s", ownershipDomain,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(solution),
+		base64.StdEncoding.EncodeToString(nonce),
+	))
+}
+
+// SignOwnership returns the proof that key owns its address for a solution
+// to the challenge of salt and nonce
+func SignOwnership(key ed25519.PrivateKey, salt, solution, nonce []byte) *OwnershipProof {
+	pk := key.PublicKey()
+	sig := ed25519.Sign(OwnershipPayload(salt, solution, nonce), key)
+	return &OwnershipProof{PublicKey: pk[:], Signature: sig[:]}
+}
+
+// VerifyOwnership checks that proof is a signature by the key of address
+// over the solution to the challenge of salt and nonce
+func VerifyOwnership(address codec.Address, proof *OwnershipProof, salt, solution, nonce []byte) error {
+	if proof == nil {
+		return ErrInvalidOwnershipProof.WithDetail("proof required")
+	}
+	if len(proof.PublicKey) != ed25519.PublicKeyLen || len(proof.Signature) != ed25519.SignatureLen {
+		return ErrInvalidOwnershipProof.WithDetail("malformed public key or signature")
+	}
+	pk := ed25519.PublicKey(proof.PublicKey)
+	if auth.NewED25519Address(pk) != address {
+		return ErrInvalidOwnershipProof.WithDetail("public key does not match the address")
+	}
+	if !ed25519.Verify(OwnershipPayload(salt, solution, nonce), pk, ed25519.Signature(proof.Signature)) {
+		return ErrInvalidOwnershipProof.WithDetail("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nuklai/nuklaivm/auth"
+)
+
+func TestVerifyOwnership(t *testing.T) {
+	key, other := newTestKey(t), newTestKey(t)
+	address := auth.NewED25519Address(key.PublicKey())
+	salt, solution, nonce := []byte("salt"), []byte("solution"), []byte("nonce")
+	otherSalt, otherNonce := []byte("other salt"), []byte("other nonce")
+
+	valid := SignOwnership(key, salt, solution, nonce)
+	// Signed by another key but presented with the key of address
+	forged := SignOwnership(other, salt, solution, nonce)
+	forged.PublicKey = valid.PublicKey
+
+	tests := []struct {
+		name     string
+		proof    *OwnershipProof
+		salt     []byte
+		solution []byte
+		ok       bool
+	}{
+		{
+			name:  "valid",
+			proof: valid,
+			ok:    true,
+		},
+		{
+			name:  "key of another address",
+			proof: SignOwnership(other, salt, solution, nonce),
+		},
+		{
+			name:  "signature by another key",
+			proof: forged,
+		},
+		{
+			name:  "wrong salt",
+			proof: valid,
+			salt:  otherSalt,
+		},
+		{
+			name:     "wrong solution",
+			proof:    valid,
+			solution: []byte("other solution"),
+		},
+		{
+			name:  "nonce from another salt",
+			proof: SignOwnership(key, salt, solution, otherNonce),
+		},
+		{
+			name:  "proof for another salt and its nonce",
+			proof: SignOwnership(key, otherSalt, solution, otherNonce),
+		},
+		{
+			name: "missing proof",
+		},
+		{
+			name:  "malformed signature",
+			proof: &OwnershipProof{PublicKey: valid.PublicKey, Signature: valid.Signature[1:]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, sol := salt, solution
+			if tt.salt != nil {
+				s = tt.salt
+			}
+			if tt.solution != nil {
+				sol = tt.solution
+			}
+			err := VerifyOwnership(address, tt.proof, s, sol, nonce)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("VerifyOwnership: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidOwnershipProof) {
+				t.Fatalf("VerifyOwnership = %v, want %v", err, ErrInvalidOwnershipProof)
+			}
+		})
+	}
+}
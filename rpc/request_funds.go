@@ -0,0 +1,311 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklaivm/challenge"
+	"github.com/nuklai/nuklaivm/consts"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultMaxRetries   = 5
+
+	// solutionSeedSize leaves room to increment the solution without
+	// exceeding the maximum solution size accepted by challenge.Verify
+	solutionSeedSize = 64
+	// attemptsPerCheck is how many candidates a worker tries between
+	// checking for cancellation
+	attemptsPerCheck = 1024
+)
+
+var (
+	big1 = big.NewInt(1)
+
+	errSaltRotated = errors.New("salt rotated")
+)
+
+// Stage identifies the step RequestFunds is at
+type Stage string
+
+const (
+	StageFetchingChallenge Stage = "fetchingChallenge"
+	StageWaiting           Stage = "waiting"
+	StageSolving           Stage = "solving"
+	StageSaltRotated       Stage = "saltRotated"
+	StageSubmitting        Stage = "submitting"
+	StageRetrying          Stage = "retrying"
+	StageCompleted         Stage = "completed"
+)
+
+// Progress is reported to the progress callback of RequestFunds
+type Progress struct {
+	Stage      Stage
+	Difficulty uint16
+	Attempts   uint64 // solutions tried for the current salt
+	TxID       ids.ID // set when completed
+	Amount     uint64 // set when completed
+	Err        error  // set when waiting or retrying
+}
+
+type requestOptions struct {
+	workers      int
+	pollInterval time.Duration
+	maxRetries   int
+	progress     func(Progress)
+	ownerKey     *ed25519.PrivateKey
+}
+
+func (o *requestOptions) report(p Progress) {
+	if o.progress != nil {
+		o.progress(p)
+	}
+}
+
+type RequestOption func(*requestOptions)
+
+// WithWorkers sets the number of goroutines searching for a solution. It
+// defaults to the number of CPUs.
+func WithWorkers(workers int) RequestOption {
+	return func(o *requestOptions) {
+		o.workers = workers
+	}
+}
+
+// WithPollInterval sets how often the challenge is refetched while solving
+// to detect salt rotations. It defaults to 5 seconds, which is also used when
+// interval is not positive.
+func WithPollInterval(interval time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithMaxRetries sets how many retryable failures are tolerated before
+// giving up. It defaults to 5.
+func WithMaxRetries(retries int) RequestOption {
+	return func(o *requestOptions) {
+		o.maxRetries = retries
+	}
+}
+
+// WithProgress sets a callback notified of every step
+func WithProgress(progress func(Progress)) RequestOption {
+	return func(o *requestOptions) {
+		o.progress = progress
+	}
+}
+
+// WithOwnershipKey signs every solution with key, the key of the address
+// funds are requested for, to prove the address is owned. Faucets requiring
+// a proof reject solutions without one.
+func WithOwnershipKey(key ed25519.PrivateKey) RequestOption {
+	return func(o *requestOptions) {
+		o.ownerKey = &key
+	}
+}
+
+// checkOwnership fails before solving a challenge whose solution could not
+// be paid without a proof
+func (o *requestOptions) checkOwnership(reply *ChallengeReply) error {
+	if reply.RequireOwnershipProof && o.ownerKey == nil {
+		return ErrInvalidOwnershipProof.WithDetail("the faucet requires a proof, see WithOwnershipKey")
+	}
+	return nil
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{
+		workers:      runtime.NumCPU(),
+		pollInterval: defaultPollInterval,
+		maxRetries:   defaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	if o.pollInterval <= 0 {
+		o.pollInterval = defaultPollInterval
+	}
+	return o
+}
+
+// RequestFunds fetches the current challenge, solves it and claims the faucet
+// amount to address. The search restarts whenever the salt rotates and
+// retryable failures are retried. It returns once the faucet has confirmed the
+// payout transaction was accepted, or when ctx is done.
+func (cli *JSONRPCClient) RequestFunds(ctx context.Context, address string, opts ...RequestOption) (ids.ID, uint64, error) {
+	o := newRequestOptions(opts)
+	if _, err := codec.ParseAddressBech32(consts.HRP, address); err != nil {
+		return ids.Empty, 0, ErrInvalidAddress.Wrap(err)
+	}
+
+	retries := 0
+	for {
+		reply, solution, err := cli.solveCurrent(ctx, o, o.checkOwnership)
+		if err != nil {
+			return ids.Empty, 0, err
+		}
+
+		var proof *OwnershipProof
+		if o.ownerKey != nil {
+			proof = SignOwnership(*o.ownerKey, reply.Salt, solution, reply.Nonce)
+		}
+
+		o.report(Progress{Stage: StageSubmitting, Difficulty: reply.Difficulty})
+		txID, amount, err := cli.SolveChallengeWithProof(ctx, address, reply.Salt, solution, proof)
+		if err == nil {
+			o.report(Progress{Stage: StageCompleted, Difficulty: reply.Difficulty, TxID: txID, Amount: amount})
+			return txID, amount, nil
+		}
+		var ferr *Error
+		if !errors.As(err, &ferr) || !ferr.Retryable || retries >= o.maxRetries {
+			return ids.Empty, 0, err
+		}
+		retries++
+		o.report(Progress{Stage: StageRetrying, Difficulty: reply.Difficulty, Err: err})
+		if err := sleepCtx(ctx, time.Duration(ferr.RetryAfter)*time.Second); err != nil {
+			return ids.Empty, 0, err
+		}
+	}
+}
+
+// Solve fetches the current challenge and searches for a solution without
+// submitting it. It returns the salt, the solution and the difficulty.
+func (cli *JSONRPCClient) Solve(ctx context.Context, opts ...RequestOption) ([]byte, []byte, uint16, error) {
+	reply, solution, err := cli.solveCurrent(ctx, newRequestOptions(opts), nil)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return reply.Salt, solution, reply.Difficulty, nil
+}
+
+// solveCurrent solves the current challenge, waiting while the faucet is
+// paused and starting over whenever the salt rotates. check, if set, can
+// reject a challenge before solving it.
+func (cli *JSONRPCClient) solveCurrent(ctx context.Context, o *requestOptions, check func(*ChallengeReply) error) (*ChallengeReply, []byte, error) {
+	for {
+		o.report(Progress{Stage: StageFetchingChallenge})
+		reply, err := cli.ChallengeInfo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if reply.Paused {
+			o.report(Progress{Stage: StageWaiting, Err: ErrMaintenance.WithDetail(reply.Message)})
+			if err := sleepCtx(ctx, o.pollInterval); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if check != nil {
+			if err := check(reply); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		o.report(Progress{Stage: StageSolving, Difficulty: reply.Difficulty})
+		solution, err := cli.solve(ctx, reply.Salt, reply.Difficulty, o)
+		if errors.Is(err, errSaltRotated) {
+			o.report(Progress{Stage: StageSaltRotated, Difficulty: reply.Difficulty})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return reply, solution, nil
+	}
+}
+
+// solve searches for a solution on o.workers goroutines, polling the faucet
+// every o.pollInterval and giving up with errSaltRotated once the salt changes
+func (cli *JSONRPCClient) solve(ctx context.Context, salt []byte, difficulty uint16, o *requestOptions) ([]byte, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	var (
+		wg       sync.WaitGroup
+		attempts atomic.Uint64
+		found    = make(chan []byte, 1)
+	)
+	defer func() {
+		cancel(nil)
+		wg.Wait()
+	}()
+
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := make([]byte, solutionSeedSize)
+			if _, err := rand.Read(start); err != nil {
+				cancel(err)
+				return
+			}
+			work := new(big.Int).SetBytes(start)
+			for n := 1; ; n++ {
+				if n%attemptsPerCheck == 0 {
+					attempts.Add(attemptsPerCheck)
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				candidate := work.Bytes()
+				if challenge.Verify(salt, candidate, difficulty) {
+					select {
+					case found <- candidate:
+					default:
+					}
+					return
+				}
+				work.Add(work, big1)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case solution := <-found:
+			return solution, nil
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-ticker.C:
+			o.report(Progress{Stage: StageSolving, Difficulty: difficulty, Attempts: attempts.Load()})
+			reply, err := cli.ChallengeInfo(ctx)
+			if err != nil {
+				// Keep solving, the salt is checked again on the next tick
+				continue
+			}
+			if !bytes.Equal(reply.Salt, salt) {
+				return nil, errSaltRotated
+			}
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -24,6 +24,14 @@ build_source() {
     echo "Building nuklai-faucet in $FAUCET_PATH"
     mkdir -p "$(dirname "$FAUCET_PATH")"
     go build -o "$FAUCET_PATH" ./
+
+    CLI_PATH=$ROOT_PATH/build/faucet-cli
+    echo "Building faucet-cli in $CLI_PATH"
+    go build -o "$CLI_PATH" ./cmd/faucet-cli
+
+    SIGNER_PATH=$ROOT_PATH/build/faucet-signer
+    echo "Building faucet-signer in $SIGNER_PATH"
+    go build -o "$SIGNER_PATH" ./cmd/faucet-signer
 }
 
 # Function to build the Docker image
@@ -0,0 +1,48 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"context"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+)
+
+var _ Signer = (*Local)(nil)
+
+// Local is a Signer holding its keys in process. The faucet uses it when no
+// remote signer is configured, and it backs the remote signer.
+type Local struct {
+	keys   map[ed25519.PublicKey]ed25519.PrivateKey
+	order  []ed25519.PublicKey
+	policy Policy
+}
+
+func NewLocal(keys []ed25519.PrivateKey, policy Policy) *Local {
+	l := &Local{keys: map[ed25519.PublicKey]ed25519.PrivateKey{}, policy: policy}
+	for _, key := range keys {
+		pk := key.PublicKey()
+		if _, ok := l.keys[pk]; ok {
+			continue
+		}
+		l.keys[pk] = key
+		l.order = append(l.order, pk)
+	}
+	return l
+}
+
+func (l *Local) PublicKeys(context.Context) ([]ed25519.PublicKey, error) {
+	return append([]ed25519.PublicKey(nil), l.order...), nil
+}
+
+func (l *Local) Sign(_ context.Context, pk ed25519.PublicKey, digest []byte) (ed25519.Signature, error) {
+	key, ok := l.keys[pk]
+	if !ok {
+		return ed25519.EmptySignature, ErrUnknownKey
+	}
+	if err := l.policy.Check(digest); err != nil {
+		return ed25519.EmptySignature, err
+	}
+	return ed25519.Sign(digest, key), nil
+}
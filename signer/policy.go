@@ -0,0 +1,93 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	hconsts "github.com/ava-labs/hypersdk/consts"
+	"github.com/nuklai/nuklaivm/actions"
+	nconsts "github.com/nuklai/nuklaivm/consts"
+
+	// Registers the actions parsed by Policy.Check
+	_ "github.com/nuklai/nuklaivm/registry"
+)
+
+// Policy restricts the transactions a signer agrees to sign. Only transfers
+// are ever signed.
+type Policy struct {
+	// MaxAmount is the largest amount a transaction may transfer in total,
+	// 0 for no limit
+	MaxAmount uint64
+	// AllowedAssets are the assets that may be transferred, only the native
+	// asset if empty
+	AllowedAssets []ids.ID
+	// AnyAsset allows every asset, ignoring AllowedAssets
+	AnyAsset bool
+}
+
+// Check parses the digest of a transaction and reports whether it complies
+// with the policy
+func (p *Policy) Check(digest []byte) error {
+	r := codec.NewReader(digest, hconsts.NetworkSizeLimit)
+	if _, err := chain.UnmarshalBase(r); err != nil {
+		return fmt.Errorf("%w: invalid transaction: %w", ErrPolicyViolation, err)
+	}
+	count := r.UnpackByte()
+	if count == 0 {
+		return fmt.Errorf("%w: transaction has no actions", ErrPolicyViolation)
+	}
+	var total uint64
+	for i := uint8(0); i < count; i++ {
+		typeID := r.UnpackByte()
+		unmarshal, ok := nconsts.ActionRegistry.LookupIndex(typeID)
+		if !ok {
+			return fmt.Errorf("%w: unknown action type %d", ErrPolicyViolation, typeID)
+		}
+		action, err := unmarshal(r)
+		if err != nil {
+			return fmt.Errorf("%w: invalid action: %w", ErrPolicyViolation, err)
+		}
+		transfer, ok := action.(*actions.Transfer)
+		if !ok {
+			return fmt.Errorf("%w: action type %d is not a transfer", ErrPolicyViolation, typeID)
+		}
+		if !p.allowed(transfer.Asset) {
+			return fmt.Errorf("%w: asset %s is not allowed", ErrPolicyViolation, transfer.Asset)
+		}
+		if transfer.Value > math.MaxUint64-total {
+			return fmt.Errorf("%w: amount overflows", ErrPolicyViolation)
+		}
+		total += transfer.Value
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("%w: invalid transaction: %w", ErrPolicyViolation, err)
+	}
+	if !r.Empty() {
+		return fmt.Errorf("%w: trailing bytes after the actions", ErrPolicyViolation)
+	}
+	if p.MaxAmount > 0 && total > p.MaxAmount {
+		return fmt.Errorf("%w: amount %d exceeds %d", ErrPolicyViolation, total, p.MaxAmount)
+	}
+	return nil
+}
+
+func (p *Policy) allowed(asset ids.ID) bool {
+	if p.AnyAsset {
+		return true
+	}
+	if len(p.AllowedAssets) == 0 {
+		return asset == ids.Empty
+	}
+	for _, allowed := range p.AllowedAssets {
+		if asset == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/nuklai/nuklai-faucet/signer"
+	"github.com/nuklai/nuklaivm/actions"
+)
+
+func digest(t *testing.T, acts ...chain.Action) []byte {
+	base := &chain.Base{Timestamp: 1_000, ChainID: ids.GenerateTestID(), MaxFee: 100}
+	d, err := chain.NewTx(base, acts).Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func transfer(asset ids.ID, value uint64) *actions.Transfer {
+	return &actions.Transfer{To: codec.CreateAddress(0, ids.GenerateTestID()), Asset: asset, Value: value}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	asset := ids.GenerateTestID()
+	other := ids.GenerateTestID()
+
+	noActions := digest(t)
+	// An action count of 1 followed by an unregistered type ID
+	unknownAction := append(append([]byte{}, noActions[:len(noActions)-1]...), 1, math.MaxUint8)
+	valid := digest(t, transfer(ids.Empty, 10))
+
+	tests := []struct {
+		name   string
+		policy signer.Policy
+		digest []byte
+		ok     bool
+	}{
+		{
+			name:   "native transfer",
+			digest: valid,
+			ok:     true,
+		},
+		{
+			name:   "native transfer with an empty allow-list",
+			digest: digest(t, transfer(asset, 10)),
+		},
+		{
+			name:   "allowed asset",
+			policy: signer.Policy{AllowedAssets: []ids.ID{asset}},
+			digest: digest(t, transfer(asset, 10)),
+			ok:     true,
+		},
+		{
+			name:   "native asset not in the allow-list",
+			policy: signer.Policy{AllowedAssets: []ids.ID{asset}},
+			digest: digest(t, transfer(ids.Empty, 10)),
+		},
+		{
+			name:   "asset not in the allow-list",
+			policy: signer.Policy{AllowedAssets: []ids.ID{asset}},
+			digest: digest(t, transfer(asset, 10), transfer(other, 10)),
+		},
+		{
+			name:   "any asset",
+			policy: signer.Policy{AllowedAssets: []ids.ID{asset}, AnyAsset: true},
+			digest: digest(t, transfer(other, 10), transfer(ids.Empty, 10)),
+			ok:     true,
+		},
+		{
+			name:   "amount at the cap",
+			policy: signer.Policy{MaxAmount: 20},
+			digest: digest(t, transfer(ids.Empty, 10), transfer(ids.Empty, 10)),
+			ok:     true,
+		},
+		{
+			name:   "total amount above the cap",
+			policy: signer.Policy{MaxAmount: 20},
+			digest: digest(t, transfer(ids.Empty, 10), transfer(ids.Empty, 11)),
+		},
+		{
+			name:   "overflowing amount",
+			digest: digest(t, transfer(ids.Empty, math.MaxUint64), transfer(ids.Empty, 1)),
+		},
+		{
+			name:   "burn",
+			policy: signer.Policy{AnyAsset: true},
+			digest: digest(t, &actions.BurnAsset{Asset: asset, Value: 10}),
+		},
+		{
+			name:   "transfer followed by a burn",
+			policy: signer.Policy{AnyAsset: true},
+			digest: digest(t, transfer(asset, 10), &actions.BurnAsset{Asset: asset, Value: 10}),
+		},
+		{
+			name:   "no actions",
+			digest: noActions,
+		},
+		{
+			name:   "unknown action",
+			digest: unknownAction,
+		},
+		{
+			name: "empty digest",
+		},
+		{
+			name:   "truncated digest",
+			digest: valid[:len(valid)-1],
+		},
+		{
+			name:   "trailing bytes",
+			digest: append(append([]byte{}, valid...), 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.digest)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Check: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, signer.ErrPolicyViolation) {
+				t.Fatalf("Check = %v, want %v", err, signer.ErrPolicyViolation)
+			}
+		})
+	}
+}
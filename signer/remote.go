@@ -0,0 +1,162 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package signer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/server"
+	"github.com/gorilla/rpc/v2/json2"
+)
+
+// JSON-RPC error codes of the remote signer, decoded back into the errors of
+// this package by Client
+const (
+	codePolicyViolation json2.ErrorCode = 2001
+	codeUnknownKey      json2.ErrorCode = 2002
+)
+
+type PublicKeysReply struct {
+	PublicKeys [][]byte `json:"publicKeys"`
+}
+
+type SignArgs struct {
+	PublicKey []byte `json:"publicKey"`
+	Digest    []byte `json:"digest"`
+}
+
+type SignReply struct {
+	Signature []byte `json:"signature"`
+}
+
+// Service exposes a Signer over JSON-RPC
+type Service struct {
+	signer Signer
+}
+
+// NewHandler returns the JSON-RPC handler of the remote signer protocol,
+// meant to be served on a Unix socket only the faucet can access
+func NewHandler(signer Signer) (http.Handler, error) {
+	return server.NewHandler(&Service{signer: signer}, "signer")
+}
+
+func (s *Service) PublicKeys(req *http.Request, _ *struct{}, reply *PublicKeysReply) error {
+	pks, err := s.signer.PublicKeys(req.Context())
+	if err != nil {
+		return err
+	}
+	for _, pk := range pks {
+		reply.PublicKeys = append(reply.PublicKeys, pk[:])
+	}
+	return nil
+}
+
+func (s *Service) Sign(req *http.Request, args *SignArgs, reply *SignReply) error {
+	if len(args.PublicKey) != ed25519.PublicKeyLen {
+		return toJSONRPCError(ErrUnknownKey)
+	}
+	sig, err := s.signer.Sign(req.Context(), ed25519.PublicKey(args.PublicKey), args.Digest)
+	if err != nil {
+		return toJSONRPCError(err)
+	}
+	reply.Signature = sig[:]
+	return nil
+}
+
+func toJSONRPCError(err error) error {
+	switch {
+	case errors.Is(err, ErrPolicyViolation):
+		return &json2.Error{Code: codePolicyViolation, Message: err.Error()}
+	case errors.Is(err, ErrUnknownKey):
+		return &json2.Error{Code: codeUnknownKey, Message: err.Error()}
+	}
+	return err
+}
+
+func fromJSONRPCError(err error) error {
+	var jerr *json2.Error
+	if !errors.As(err, &jerr) {
+		return err
+	}
+	switch jerr.Code {
+	case codePolicyViolation:
+		// The message already starts with ErrPolicyViolation
+		return fmt.Errorf("%w%s", ErrPolicyViolation, strings.TrimPrefix(jerr.Message, ErrPolicyViolation.Error()))
+	case codeUnknownKey:
+		return ErrUnknownKey
+	}
+	return err
+}
+
+var _ Signer = (*Client)(nil)
+
+// Client is a Signer calling a remote signer on a Unix socket
+type Client struct {
+	cli *http.Client
+}
+
+func NewClient(socket string) *Client {
+	var d net.Dialer
+	return &Client{cli: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+		Timeout: signTimeout,
+	}}
+}
+
+func (c *Client) PublicKeys(ctx context.Context) ([]ed25519.PublicKey, error) {
+	reply := new(PublicKeysReply)
+	if err := c.call(ctx, "publicKeys", struct{}{}, reply); err != nil {
+		return nil, err
+	}
+	pks := make([]ed25519.PublicKey, len(reply.PublicKeys))
+	for i, pk := range reply.PublicKeys {
+		if len(pk) != ed25519.PublicKeyLen {
+			return nil, fmt.Errorf("remote signer returned an invalid public key of %d bytes", len(pk))
+		}
+		pks[i] = ed25519.PublicKey(pk)
+	}
+	return pks, nil
+}
+
+func (c *Client) Sign(ctx context.Context, pk ed25519.PublicKey, digest []byte) (ed25519.Signature, error) {
+	reply := new(SignReply)
+	if err := c.call(ctx, "sign", &SignArgs{PublicKey: pk[:], Digest: digest}, reply); err != nil {
+		return ed25519.EmptySignature, err
+	}
+	// The signer is not trusted to return a valid signature
+	if len(reply.Signature) != ed25519.SignatureLen || !ed25519.Verify(digest, pk, ed25519.Signature(reply.Signature)) {
+		return ed25519.EmptySignature, errors.New("remote signer returned an invalid signature")
+	}
+	return ed25519.Signature(reply.Signature), nil
+}
+
+func (c *Client) call(ctx context.Context, method string, args any, reply any) error {
+	body, err := json2.EncodeClientRequest("signer."+method, args)
+	if err != nil {
+		return err
+	}
+	// The host is ignored, requests are always sent to the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://signer/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote signer unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	return fromJSONRPCError(json2.DecodeClientResponse(resp.Body, reply))
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2024, Nuklai. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package signer abstracts signing faucet transactions so the private keys
+// do not have to live in the faucet process. A Signer checks every
+// transaction against its Policy before signing it.
+package signer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ava-labs/hypersdk/chain"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/nuklai/nuklaivm/auth"
+)
+
+// signTimeout bounds a signature, chain.AuthFactory does not pass a context
+const signTimeout = 10 * time.Second
+
+var (
+	ErrPolicyViolation = errors.New("transaction violates the signer policy")
+	ErrUnknownKey      = errors.New("signer does not hold the key")
+)
+
+// Signer signs transaction digests with the ed25519 keys it holds
+type Signer interface {
+	// PublicKeys returns the public key of every key held, in a stable order
+	PublicKeys(ctx context.Context) ([]ed25519.PublicKey, error)
+	// Sign signs the digest of a transaction with the key of pk if the
+	// transaction complies with the policy of the signer
+	Sign(ctx context.Context, pk ed25519.PublicKey, digest []byte) (ed25519.Signature, error)
+}
+
+var _ chain.AuthFactory = (*Factory)(nil)
+
+// Factory signs transactions of a single key through a Signer
+type Factory struct {
+	signer Signer
+	pk     ed25519.PublicKey
+}
+
+func NewFactory(signer Signer, pk ed25519.PublicKey) *Factory {
+	return &Factory{signer: signer, pk: pk}
+}
+
+func (f *Factory) Sign(msg []byte) (chain.Auth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), signTimeout)
+	defer cancel()
+
+	sig, err := f.signer.Sign(ctx, f.pk, msg)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.ED25519{Signer: f.pk, Signature: sig}, nil
+}
+
+func (*Factory) MaxUnits() (uint64, uint64) {
+	return auth.ED25519Size, auth.ED25519ComputeUnits
+}